@@ -0,0 +1,169 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"net/http/pprof"
+	"runtime"
+	rpprof "runtime/pprof"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// AdminServer 是独立于业务 engine 的管理端口，提供 pprof、运行时统计与日志级别调整。
+// 只应绑定在内网 / 本机地址上，不要暴露到公网。
+type AdminServer struct {
+	Engine  *gin.Engine
+	Handler *http.Server
+
+	serveErr chan error
+}
+
+// NewAdminEngine 创建管理端口的路由：
+//   - GET  /debug/pprof/*     标准 pprof
+//   - GET  /debug/goroutines  全部 goroutine 堆栈（文本）
+//   - GET  /stats             运行时、Go() 协程池、pgxpool、go-redis 连接池统计
+//   - GET  /loglevel          当前日志级别
+//   - PUT  /loglevel          调整日志级别，?level=debug 或 {"level":"debug"}
+//   - GET  /config            当前生效的配置及来源，密码与 DSN 凭据已打码
+//   - GET  /migrations        RegisterMigrator 注册的迁移执行状态
+func NewAdminEngine() *gin.Engine {
+	engine := gin.New()
+	engine.Use(Recover())
+	engine.NoRoute(NotFound())
+
+	debug := engine.Group("/debug/pprof")
+	debug.GET("/", gin.WrapF(pprof.Index))
+	debug.GET("/cmdline", gin.WrapF(pprof.Cmdline))
+	debug.GET("/profile", gin.WrapF(pprof.Profile))
+	debug.GET("/symbol", gin.WrapF(pprof.Symbol))
+	debug.POST("/symbol", gin.WrapF(pprof.Symbol))
+	debug.GET("/trace", gin.WrapF(pprof.Trace))
+	debug.GET("/:name", func(ctx *gin.Context) {
+		pprof.Handler(ctx.Param("name")).ServeHTTP(ctx.Writer, ctx.Request)
+	})
+
+	engine.GET("/debug/goroutines", func(ctx *gin.Context) {
+		ctx.Header("Content-Type", "text/plain; charset=utf-8")
+		_ = rpprof.Lookup("goroutine").WriteTo(ctx.Writer, 2)
+	})
+	engine.GET("/stats", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, Result(RuntimeStats()))
+	})
+	engine.GET("/config", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, Result(ConfigDump()))
+	})
+	engine.GET("/migrations", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, Result(MigrationsStatus(ctx.Request.Context())))
+	})
+	engine.GET("/loglevel", func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, Result(M{"level": LogLevel().String()}))
+	})
+	engine.PUT("/loglevel", func(ctx *gin.Context) {
+		var req struct {
+			Level string `json:"level" form:"level"`
+		}
+		_ = ctx.ShouldBind(&req)
+		if req.Level == "" {
+			req.Level = ctx.Query("level")
+		}
+		var l slog.Level
+		if err := l.UnmarshalText([]byte(req.Level)); err != nil {
+			ctx.JSON(http.StatusBadRequest, &ErrorCode{Status: http.StatusBadRequest, Code: ERR_PARAM.Code, Msg: "无效的日志级别: " + req.Level})
+			return
+		}
+		prev := LogLevel()
+		SetLogLevel(l)
+		slog.Warn("日志级别已调整", "from", prev.String(), "to", l.String())
+		ctx.JSON(http.StatusOK, Result(M{"level": l.String()}))
+	})
+	return engine
+}
+
+// RuntimeStats 汇总运行时与各连接池的统计信息，未启用 / 未就绪的依赖不输出。
+func RuntimeStats() M {
+	var mem runtime.MemStats
+	runtime.ReadMemStats(&mem)
+	inUse, size := GoroutinePoolStats()
+	stats := M{
+		"goroutines": runtime.NumGoroutine(),
+		"goPool":     M{"inUse": inUse, "size": size},
+		"memory": M{
+			"alloc":      mem.Alloc,
+			"heapInuse":  mem.HeapInuse,
+			"sys":        mem.Sys,
+			"numGC":      mem.NumGC,
+			"pauseTotal": time.Duration(mem.PauseTotalNs).String(),
+		},
+	}
+	// 默认实例输出为 "postgres"，命名实例为 "postgres:<name>"，只读副本为 "postgres-replica-<n>"。
+	for _, st := range PostgresStats() {
+		stats[st.Name] = M{
+			"maxConns":             st.MaxConns,
+			"totalConns":           st.TotalConns,
+			"acquiredConns":        st.AcquiredConns,
+			"idleConns":            st.IdleConns,
+			"constructingConns":    st.ConstructingConns,
+			"saturation":           st.Saturation(),
+			"acquireCount":         st.AcquireCount,
+			"acquireDuration":      st.AcquireDuration.String(),
+			"emptyAcquireCount":    st.EmptyAcquireCount,
+			"emptyAcquireWaitTime": st.EmptyAcquireWaitTime.String(),
+			"canceledAcquireCount": st.CanceledAcquireCount,
+		}
+	}
+	if client := defaultRedis.Load(); client != nil {
+		stats["redis"] = client.PoolStats()
+	}
+	return stats
+}
+
+// ServerRun 同步绑定 ADMIN_SERVER_ADDR 并在后台 Serve；地址未配置时不启用，返回 nil。
+func (a *AdminServer) ServerRun() error {
+	if !HasAdmin() {
+		return nil
+	}
+	if a.Engine == nil {
+		a.Engine = NewAdminEngine()
+	}
+	if a.Handler == nil {
+		a.Handler = &http.Server{
+			Addr:    conf().Server.AdminAddr,
+			Handler: a.Engine,
+		}
+	}
+	ln, err := listen(a.Handler.Addr)
+	if err != nil {
+		return fmt.Errorf("Admin 监听失败 addr=%s: %w", a.Handler.Addr, err)
+	}
+	slog.Info("Admin server running", "addr", ln.Addr().String())
+	a.serveErr = make(chan error, 1)
+	go func() {
+		if err := a.Handler.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
+			slog.Error("Admin server serve failed", "addr", ln.Addr().String(), "err", err)
+			a.serveErr <- fmt.Errorf("Admin serve 失败 addr=%s: %w", ln.Addr().String(), err)
+		}
+	}()
+	return nil
+}
+
+// serveErrors 返回 Serve 阶段的异常；未启动时为 nil channel。
+func (a *AdminServer) serveErrors() <-chan error {
+	return a.serveErr
+}
+
+func (a *AdminServer) ServerStop() {
+	if a.Handler == nil {
+		return
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	if err := a.Handler.Shutdown(ctx); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		slog.Error("Admin server shutdown failed", "err", err)
+	}
+	slog.Info("Admin server stopped")
+}
@@ -0,0 +1,39 @@
+package gowk
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestAdminLogLevel(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := LogLevel()
+	defer SetLogLevel(prev)
+
+	engine := NewAdminEngine()
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/loglevel?level=debug", nil))
+	if w.Code != http.StatusOK || LogLevel() != slog.LevelDebug {
+		t.Fatalf("PUT /loglevel: code=%d level=%v", w.Code, LogLevel())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodPut, "/loglevel?level=loud", nil))
+	if w.Code != http.StatusBadRequest || LogLevel() != slog.LevelDebug {
+		t.Fatalf("invalid level: code=%d level=%v", w.Code, LogLevel())
+	}
+
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/stats", nil))
+	var resp struct {
+		Data map[string]any `json:"data"`
+	}
+	if err := json.Unmarshal(w.Body.Bytes(), &resp); err != nil || resp.Data["goroutines"] == nil {
+		t.Fatalf("GET /stats: %s", w.Body.String())
+	}
+}
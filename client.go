@@ -6,6 +6,7 @@ import (
 	"errors"
 	"fmt"
 	"sync"
+	"sync/atomic"
 
 	"github.com/gin-gonic/gin"
 )
@@ -14,7 +15,9 @@ const ContextClientKey = "AKEY_CONTEXT_CLIENT_KEY"
 const redisClientPrefix = "AKEY_CLIENT_"
 
 var _defaultClientHandler ClientHandler
-var _defaultClientKeyNames = []string{"X-API-Key", "akey"}
+
+// _defaultClientKeyNames 读取 API key 的 header 名，初始取 Config.Auth.ClientKeyNames，配置重载时可能被并发修改。
+var _defaultClientKeyNames atomic.Pointer[[]string]
 
 type Client struct {
 	Key     string `json:"key"`
@@ -49,7 +52,7 @@ func SetClientKeyName(name string) {
 	if name == "" {
 		return
 	}
-	_defaultClientKeyNames = []string{name}
+	_defaultClientKeyNames.Store(&[]string{name})
 }
 func SetClientKeyNames(names ...string) {
 	var filtered []string
@@ -60,12 +63,19 @@ func SetClientKeyNames(names ...string) {
 		filtered = append(filtered, name)
 	}
 	if len(filtered) > 0 {
-		_defaultClientKeyNames = filtered
+		_defaultClientKeyNames.Store(&filtered)
 	}
 }
 
+func clientKeyNames() []string {
+	if names := _defaultClientKeyNames.Load(); names != nil {
+		return *names
+	}
+	return nil
+}
+
 func clientKeyValue(ctx *gin.Context) string {
-	for _, name := range _defaultClientKeyNames {
+	for _, name := range clientKeyNames() {
 		if value := ctx.Request.Header.Get(name); value != "" {
 			return value
 		}
@@ -141,6 +151,7 @@ func (d *redisClientStore) LoadClient(ctx context.Context, key string) (*Client,
 }
 
 func init() {
+	SetClientKeyNames(conf().Auth.ClientKeyNames...)
 	if HasRedis() {
 		_defaultClientHandler = &redisClientStore{}
 	} else {
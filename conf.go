@@ -1,66 +1,230 @@
 package gowk
 
 import (
+	"log/slog"
 	"os"
-	"strconv"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"time"
 )
 
-var (
-	baseURL       = getEnv("BASE_URL", "http://localhost:3030")
-	databaseDsn   = getEnv("DATABASE_DSN", "")
-	redisAddr     = getEnv("REDIS_ADDR", "")
-	redisPassword = getEnv("REDIS_PASSWORD", "")
-	redisDB       = mustAtoi(getEnv("REDIS_DB", "0"))
-)
+// Config 是 gowk 自身的配置。每个字段都可以来自配置文件（conf tag）或环境变量（env tag，
+// 也可用 <env>_FILE 指向 secret 文件），未设置时取 default tag；加载规则见 LoadConfig。
+//
+// 业务配置可以把它作为一个字段嵌进自己的结构体，一次 LoadConfig 同时完成两者的绑定与校验：
+//
+//	type AppConfig struct {
+//		gowk.Config `conf:"gowk"`
+//		FeatureX    bool `conf:"featureX" env:"FEATURE_X"`
+//	}
+type Config struct {
+	BaseURL  string     `conf:"baseURL" env:"BASE_URL" default:"http://localhost:3030" validate:"omitempty,url"`
+	LogLevel slog.Level `conf:"logLevel" env:"LOG_LEVEL" default:"info"`
 
-// 后台重试参数。均走 time.ParseDuration 解析；未设置、解析失败或 <= 0 时回落到默认值。
-// 语义：每轮 attempt 失败后 sleep = min(backoff, max)，随后 backoff *= 2，最终被 max 封顶。
-var (
-	pgRetryBaseInterval = getEnvDuration("DATABASE_RETRY_BASE_INTERVAL", 2*time.Second)
-	pgRetryMaxInterval  = getEnvDuration("DATABASE_RETRY_MAX_INTERVAL", 30*time.Second)
-	pgPingTimeout       = getEnvDuration("DATABASE_PING_TIMEOUT", 5*time.Second)
+	Server    ServerSettings  `conf:"server"`
+	Lifecycle LifecycleConfig `conf:"lifecycle"`
+	Database  DatabaseConfig  `conf:"database"`
+	Redis     RedisConfig     `conf:"redis"`
+	Health    HealthConfig    `conf:"health"`
+	TLS       TLSConfig       `conf:"tls"`
+	Auth      AuthConfig      `conf:"auth"`
+	Page      PageConfig      `conf:"page"`
+}
 
-	redisRetryBaseInterval = getEnvDuration("REDIS_RETRY_BASE_INTERVAL", 2*time.Second)
-	redisRetryMaxInterval  = getEnvDuration("REDIS_RETRY_MAX_INTERVAL", 30*time.Second)
-	redisPingTimeout       = getEnvDuration("REDIS_PING_TIMEOUT", 5*time.Second)
-)
+// ServerSettings 各监听地址。地址支持 "unix:///path" 形式的 Unix socket。
+type ServerSettings struct {
+	HTTPAddr string `conf:"httpAddr" env:"HTTP_SERVER_ADDR" default:":3030" validate:"required"`
+	// GRPCAddr 未配置不启用 gRPC；与 HTTPAddr 相同时为单端口模式。
+	GRPCAddr string `conf:"grpcAddr" env:"GRPC_SERVER_ADDR"`
+	// HTTP3Addr HTTP/3（QUIC, UDP）监听地址，未配置不启用；需要同时配置 TLS 证书。
+	HTTP3Addr string `conf:"http3Addr" env:"HTTP3_SERVER_ADDR"`
+	// AdminAddr 管理端口（pprof / stats / loglevel），未配置不启用；建议只绑定内网地址，如 127.0.0.1:6060。
+	AdminAddr string `conf:"adminAddr" env:"ADMIN_SERVER_ADDR"`
+	// SniffTimeout 单端口模式下嗅探协议的读超时。
+	SniffTimeout time.Duration `conf:"sniffTimeout" env:"SINGLE_PORT_SNIFF_TIMEOUT" default:"5s" validate:"gt=0"`
+	// UnixSocketMode / UnixSocketGroup 创建 Unix socket 文件后设置的权限（八进制，如 0660）与属组，未配置时保持默认。
+	UnixSocketMode  os.FileMode `conf:"unixSocketMode" env:"UNIX_SOCKET_MODE" validate:"lte=0777"`
+	UnixSocketGroup string      `conf:"unixSocketGroup" env:"UNIX_SOCKET_GROUP"`
+}
+
+// LifecycleConfig 启动、关闭与重启相关的超时。
+type LifecycleConfig struct {
+	// StartupWaitTimeout 必需依赖在监听之前就绪的上限，超时启动失败。
+	StartupWaitTimeout time.Duration `conf:"startupWaitTimeout" env:"STARTUP_WAIT_TIMEOUT" default:"30s" validate:"gt=0"`
+	// HookTimeout 生命周期钩子未单独设置 Timeout 时的默认超时。
+	HookTimeout time.Duration `conf:"hookTimeout" env:"HOOK_TIMEOUT" default:"10s" validate:"gt=0"`
+	// RestartReadyTimeout 零停机重启时等待新进程就绪的上限，超时后杀掉新进程，旧进程继续服务。
+	RestartReadyTimeout time.Duration `conf:"restartReadyTimeout" env:"RESTART_READY_TIMEOUT" default:"60s" validate:"gt=0"`
+	// ConfigWatchInterval 检查配置文件是否变化的间隔，变化后自动 ReloadConfig；未配置不监视。
+	ConfigWatchInterval time.Duration `conf:"configWatchInterval" env:"CONFIG_WATCH_INTERVAL" validate:"gte=0"`
+}
+
+// DatabaseConfig Postgres 连接与后台重试参数。
+// 重试语义：每轮 attempt 失败后 sleep = min(backoff, max)，随后 backoff *= 2，最终被 max 封顶。
+type DatabaseConfig struct {
+	DSN string `conf:"dsn" env:"DATABASE_DSN"`
+	// Replicas 默认实例的只读副本 DSN（逗号分隔），ReadOnly / WithReadOnly 标记的请求优先使用，见 Postgres。
+	Replicas []string `conf:"replicas" env:"DATABASE_REPLICA_DSNS" validate:"dive,required"`
+	// Named 命名实例的 DSN，环境变量 DATABASE_DSN_<NAME> 对应键 <name>（小写），通过 PostgresNamed 访问；
+	// 重试与探活参数与默认实例共用。
+	Named map[string]string `conf:"named" envPrefix:"DATABASE_DSN_"`
+	// Required 为 true 时 Run 等 Postgres 就绪后才监听，未声明的依赖保持"降级 + 后台重试"。
+	Required          bool          `conf:"required" env:"DATABASE_REQUIRED"`
+	RetryBaseInterval time.Duration `conf:"retryBaseInterval" env:"DATABASE_RETRY_BASE_INTERVAL" default:"2s" validate:"gt=0"`
+	RetryMaxInterval  time.Duration `conf:"retryMaxInterval" env:"DATABASE_RETRY_MAX_INTERVAL" default:"30s" validate:"gtefield=RetryBaseInterval"`
+	// PingTimeout 单次 NewWithConfig + Ping 的超时，也用于就绪后的周期探活。
+	PingTimeout time.Duration `conf:"pingTimeout" env:"DATABASE_PING_TIMEOUT" default:"5s" validate:"gt=0"`
+	// Pool 连接池参数，所有实例（含命名实例与只读副本）共用。
+	Pool PoolConfig `conf:"pool"`
+}
+
+// PoolConfig pgxpool 参数。未配置（零值）的项保持 DSN 中 pool_max_conns 等参数或 pgx 的默认值，配置后优先于 DSN。
+type PoolConfig struct {
+	MaxConns int32 `conf:"maxConns" env:"DATABASE_MAX_CONNS" validate:"gte=0"`
+	MinConns int32 `conf:"minConns" env:"DATABASE_MIN_CONNS" validate:"gte=0"`
+	// MaxConnLifetime / MaxConnIdleTime 连接最长存活与最长空闲时间，超过后由后台健康检查关闭。
+	MaxConnLifetime time.Duration `conf:"maxConnLifetime" env:"DATABASE_MAX_CONN_LIFETIME" validate:"gte=0"`
+	MaxConnIdleTime time.Duration `conf:"maxConnIdleTime" env:"DATABASE_MAX_CONN_IDLE_TIME" validate:"gte=0"`
+	// HealthCheckPeriod pgxpool 检查空闲连接的间隔。
+	HealthCheckPeriod time.Duration `conf:"healthCheckPeriod" env:"DATABASE_HEALTH_CHECK_PERIOD" validate:"gte=0"`
+	// StatementCacheMode 对应 pgx 的 default_query_exec_mode；经 PgBouncer 等事务级连接池时通常需要 exec 或 simple_protocol。
+	StatementCacheMode string `conf:"statementCacheMode" env:"DATABASE_STATEMENT_CACHE_MODE" validate:"omitempty,oneof=cache_statement cache_describe describe_exec exec simple_protocol"`
+	// StatsLogInterval 周期输出连接池获取等待与饱和度日志的间隔，未配置不输出。
+	StatsLogInterval time.Duration `conf:"statsLogInterval" env:"DATABASE_STATS_LOG_INTERVAL" validate:"gte=0"`
+}
+
+// RedisConfig Redis 连接与后台重试参数，重试语义同 DatabaseConfig。
+type RedisConfig struct {
+	Addr     string `conf:"addr" env:"REDIS_ADDR"`
+	Password string `conf:"password" env:"REDIS_PASSWORD" secret:"true"`
+	DB       int    `conf:"db" env:"REDIS_DB" validate:"gte=0"`
+	// Required 为 true 时 Run 触发 Redis 初始化并等其就绪后才监听。
+	Required          bool          `conf:"required" env:"REDIS_REQUIRED"`
+	RetryBaseInterval time.Duration `conf:"retryBaseInterval" env:"REDIS_RETRY_BASE_INTERVAL" default:"2s" validate:"gt=0"`
+	RetryMaxInterval  time.Duration `conf:"retryMaxInterval" env:"REDIS_RETRY_MAX_INTERVAL" default:"30s" validate:"gtefield=RetryBaseInterval"`
+	PingTimeout       time.Duration `conf:"pingTimeout" env:"REDIS_PING_TIMEOUT" default:"5s" validate:"gt=0"`
+}
+
+// HealthConfig 依赖探活与自定义健康检查参数。
+type HealthConfig struct {
+	// Interval 依赖连上之后的周期探活间隔，探活超时复用各自的 PingTimeout；也是 gRPC health 服务的同步间隔。
+	Interval time.Duration `conf:"interval" env:"HEALTH_CHECK_INTERVAL" default:"10s" validate:"gt=0"`
+	// Timeout / CacheTTL RegisterHealthCheck 注册的自定义检查：单次超时与结果缓存时长。
+	Timeout  time.Duration `conf:"timeout" env:"HEALTH_CHECK_TIMEOUT" default:"2s" validate:"gt=0"`
+	CacheTTL time.Duration `conf:"cacheTTL" env:"HEALTH_CHECK_CACHE_TTL" default:"5s" validate:"gt=0"`
+}
+
+// TLSConfig 证书文件配置。CertFile / KeyFile 同时配置才启用，HTTP 与 gRPC 共用；
+// ClientCAFile 配置后校验客户端证书。SetTLS 的优先级高于这里。
+type TLSConfig struct {
+	CertFile     string `conf:"certFile" env:"TLS_CERT_FILE" validate:"required_with=KeyFile"`
+	KeyFile      string `conf:"keyFile" env:"TLS_KEY_FILE" validate:"required_with=CertFile"`
+	ClientCAFile string `conf:"clientCAFile" env:"TLS_CLIENT_CA_FILE"`
+	// ClientAuth 可选 none/request/require/verify-if-given/require-and-verify。
+	ClientAuth     string        `conf:"clientAuth" env:"TLS_CLIENT_AUTH" validate:"omitempty,oneof=none request require verify-if-given require-and-verify"`
+	ReloadInterval time.Duration `conf:"reloadInterval" env:"TLS_RELOAD_INTERVAL" default:"10s" validate:"gt=0"`
+}
 
+// AuthConfig 登录与 API key 认证参数，修改后重载即生效。
+type AuthConfig struct {
+	// TokenTimeout Login 签发 token 的有效期，对应 SetTokenTimeout。
+	TokenTimeout time.Duration `conf:"tokenTimeout" env:"TOKEN_TIMEOUT" default:"720h" validate:"gte=1s"`
+	// ClientKeyNames CheckClient 读取 API key 的 header（gRPC 为 metadata）名，按顺序取第一个非空值，对应 SetClientKeyNames。
+	ClientKeyNames []string `conf:"clientKeyNames" env:"CLIENT_KEY_NAMES" default:"X-API-Key,akey" validate:"min=1,dive,required"`
+}
+
+// PageConfig 列表分页参数，见 PageModel.Normalize。
+type PageConfig struct {
+	DefaultSize int64 `conf:"defaultSize" env:"PAGE_DEFAULT_SIZE" default:"10" validate:"gte=1"`
+	// MaxSize 单页最大条数，超过时截断，避免 size=1000000 这类请求打到数据库。
+	MaxSize int64 `conf:"maxSize" env:"PAGE_MAX_SIZE" default:"100" validate:"gtefield=DefaultSize"`
+	// Modes 各资源列表的分页方式 offset（默认）/ cursor，键为 Register 时的完整路径去掉首尾 / 后把 / 和 - 换成 _（小写），
+	// 如 /api/users 对应 api_users，环境变量 PAGE_MODE_API_USERS；在 Register 时读取，重载不生效。见 CursorPage。
+	Modes map[string]string `conf:"modes" envPrefix:"PAGE_MODE_" validate:"dive,oneof=offset cursor"`
+	// CursorSecret 游标签名密钥，多实例部署需要配置为相同的值；注册了游标分页接口而未配置时 RunContext 拒绝启动。
+	CursorSecret string `conf:"cursorSecret" env:"PAGE_CURSOR_SECRET" secret:"true"`
+}
+
+// currentConfig 是当前生效的 gowk 配置，LoadConfig / ReloadConfig 成功后整体替换（保留 configOverrides），读取一律经 conf()。
+// 包初始化时按默认值与环境变量加载 envConfig；环境变量有误时不在 init 里 panic，
+// 而是记在 confErr 中，推迟到 RunContext 返回。
 var (
-	httpServerAddr = getEnv("HTTP_SERVER_ADDR", ":3030")
-	grpcServerAddr = getEnv("GRPC_SERVER_ADDR", "")
+	currentConfig                  atomic.Pointer[Config]
+	envConfig, envOrigins, confErr = loadEnvConfig()
+	configMu                       sync.Mutex
 )
 
-func SetHTTPServerAddr(addr string) { httpServerAddr = addr }
-func SetGRPCServerAddr(addr string) { grpcServerAddr = addr }
+func loadEnvConfig() (*Config, map[string]configOrigin, error) {
+	c := &Config{}
+	origins, err := bindConfig(c, nil)
+	return c, origins, err
+}
 
-func getEnv(key, defaultValue string) string {
-	if v := os.Getenv(key); v != "" {
-		return v
+func conf() *Config {
+	if c := currentConfig.Load(); c != nil {
+		return c
 	}
-	return defaultValue
+	return envConfig
 }
 
-func getEnvDuration(key string, defaultValue time.Duration) time.Duration {
-	if v := os.Getenv(key); v != "" {
-		if d, err := time.ParseDuration(v); err == nil && d > 0 {
-			return d
+// updateConfig 以写时复制的方式修改当前配置，供 Set* 使用。
+func updateConfig(fn func(c *Config)) {
+	configMu.Lock()
+	defer configMu.Unlock()
+	c := *conf()
+	fn(&c)
+	currentConfig.Store(&c)
+}
+
+// configOverrides 代码中通过 Set* 修改的配置项，键为配置路径（如 server.grpcAddr），由 configMu 保护。
+// LoadConfig / ReloadConfig 生效新配置时重新应用，重载不会把它们改回配置文件或环境变量中的值。
+var configOverrides = map[string]func(c *Config){}
+
+// overrideConfig 修改配置并记为 keys 的代码覆盖，见 configOverrides。
+func overrideConfig(fn func(c *Config), keys ...string) {
+	updateConfig(func(c *Config) {
+		for _, key := range keys {
+			configOverrides[key] = fn
 		}
-	}
-	return defaultValue
+		fn(c)
+	})
 }
 
-func mustAtoi(s string) int {
-	if v, err := strconv.Atoi(s); err == nil {
-		return v
-	}
-	return 0
+// configError 包初始化时环境变量的解析错误，LoadConfig / ReloadConfig 成功后清除。
+func configError() error {
+	configMu.Lock()
+	defer configMu.Unlock()
+	return confErr
+}
+
+// CurrentConfig 返回当前生效的 gowk 配置副本。
+func CurrentConfig() Config { return *conf() }
+
+func SetHTTPServerAddr(addr string) {
+	overrideConfig(func(c *Config) { c.Server.HTTPAddr = addr }, "server.httpAddr")
+}
+func SetGRPCServerAddr(addr string) {
+	overrideConfig(func(c *Config) { c.Server.GRPCAddr = addr }, "server.grpcAddr")
+}
+func SetHTTP3ServerAddr(addr string) {
+	overrideConfig(func(c *Config) { c.Server.HTTP3Addr = addr }, "server.http3Addr")
+}
+func SetAdminServerAddr(addr string) {
+	overrideConfig(func(c *Config) { c.Server.AdminAddr = addr }, "server.adminAddr")
+}
+
+// SetUnixSocketPermissions 设置 Unix socket 文件的权限与属组，group 为空时不修改属组。
+func SetUnixSocketPermissions(mode os.FileMode, group string) {
+	overrideConfig(func(c *Config) { c.Server.UnixSocketMode, c.Server.UnixSocketGroup = mode, group },
+		"server.unixSocketMode", "server.unixSocketGroup")
 }
 
-func HasRedis() bool { return redisAddr != "" }
-func HasGRPC() bool  { return grpcServerAddr != "" }
+func HasRedis() bool { return conf().Redis.Addr != "" }
+func HasGRPC() bool  { return conf().Server.GRPCAddr != "" }
+func HasHTTP3() bool { return conf().Server.HTTP3Addr != "" }
+func HasAdmin() bool { return conf().Server.AdminAddr != "" }
 
 func BaseURL() string {
-	return strings.TrimSuffix(baseURL, "/")
+	return strings.TrimSuffix(conf().BaseURL, "/")
 }
@@ -0,0 +1,588 @@
+package gowk
+
+import (
+	"encoding"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/go-playground/validator/v10"
+	"github.com/goccy/go-yaml"
+	"github.com/pelletier/go-toml/v2"
+)
+
+// LoadConfig 把默认值、配置文件与环境变量依次绑定到 cfg（结构体指针），后者覆盖前者：
+//   - default tag 为默认值，只填充仍为零值的字段，代码里预先赋的值优先；
+//   - files 按顺序加载，按扩展名识别 YAML（.yaml/.yml）、TOML（.toml）、JSON（.json），
+//     键名取 conf tag（未设置时取字段名，不区分大小写）；files 为空时读取 CONFIG_FILE（逗号分隔）；
+//   - env tag 指定的环境变量非空时覆盖前面的值，切片以逗号分隔；变量未设置时读取 <变量名>_FILE 指向的文件内容；
+//   - 最后按 validate tag（go-playground/validator）校验。
+//
+// 所有绑定与校验错误会汇总返回，错误信息带有配置路径或环境变量名。
+// cfg 本身是 *Config、或含有 Config 类型的字段（含匿名嵌入）时，成功后其中的 Config 作为 gowk 自身配置生效。
+// 之后 ReloadConfig（SIGHUP / 文件变化）按同样的类型与文件重新加载，见 OnConfigChange。
+func LoadConfig(cfg any, files ...string) error {
+	if len(files) == 0 {
+		if v := os.Getenv("CONFIG_FILE"); v != "" {
+			files = splitList(v)
+		}
+	}
+	configSource.mu.Lock()
+	defer configSource.mu.Unlock()
+	var base any
+	if rv := reflect.ValueOf(cfg); rv.Kind() == reflect.Pointer && !rv.IsNil() {
+		base = cloneConfig(rv).Interface()
+	}
+	origins, err := bindConfig(cfg, files)
+	if err != nil {
+		return err
+	}
+	old := configSource.cfg
+	configSource.cfg, configSource.base, configSource.files, configSource.origins = cfg, base, files, origins
+	publishConfig(old, cfg)
+	return nil
+}
+
+// cloneConfig 深拷贝配置值（结构体、指针、切片与 map），绑定时会原地修改 map 与指针指向的值，
+// 浅拷贝的快照会跟着变化。
+func cloneConfig(v reflect.Value) reflect.Value {
+	switch v.Kind() {
+	case reflect.Pointer:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.New(v.Type().Elem())
+		c.Elem().Set(cloneConfig(v.Elem()))
+		return c
+	case reflect.Struct:
+		c := reflect.New(v.Type()).Elem()
+		c.Set(v)
+		for i := range v.NumField() {
+			if v.Type().Field(i).IsExported() {
+				c.Field(i).Set(cloneConfig(v.Field(i)))
+			}
+		}
+		return c
+	case reflect.Slice:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeSlice(v.Type(), v.Len(), v.Len())
+		for i := range v.Len() {
+			c.Index(i).Set(cloneConfig(v.Index(i)))
+		}
+		return c
+	case reflect.Map:
+		if v.IsNil() {
+			return v
+		}
+		c := reflect.MakeMapWithSize(v.Type(), v.Len())
+		for it := v.MapRange(); it.Next(); {
+			c.SetMapIndex(it.Key(), cloneConfig(it.Value()))
+		}
+		return c
+	}
+	return v
+}
+
+// findConfig 在 v 中找到 Config 类型的值（广度优先，只找第一个）。
+func findConfig(v reflect.Value) *Config {
+	for v.Kind() == reflect.Pointer {
+		if v.IsNil() {
+			return nil
+		}
+		v = v.Elem()
+	}
+	if v.Kind() != reflect.Struct {
+		return nil
+	}
+	if c, ok := v.Addr().Interface().(*Config); ok {
+		return c
+	}
+	for i := range v.NumField() {
+		if f := v.Field(i); f.Type() == reflect.TypeFor[Config]() && f.CanAddr() {
+			return f.Addr().Interface().(*Config)
+		}
+	}
+	for i := range v.NumField() {
+		if !v.Type().Field(i).IsExported() {
+			continue
+		}
+		if c := findConfig(v.Field(i)); c != nil {
+			return c
+		}
+	}
+	return nil
+}
+
+// configOrigin 记录一个配置项最终取值的来源，用于 ConfigDump。
+type configOrigin struct {
+	source string // default / file / env
+	from   string // 文件路径或环境变量名
+}
+
+// configBinder 一次绑定过程的状态：汇总的错误与各配置路径的来源。
+type configBinder struct {
+	errs    []error
+	origins map[string]configOrigin
+}
+
+func bindConfig(cfg any, files []string) (map[string]configOrigin, error) {
+	rv := reflect.ValueOf(cfg)
+	if rv.Kind() != reflect.Pointer || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return nil, fmt.Errorf("LoadConfig 需要结构体指针，实际为 %T", cfg)
+	}
+	root := rv.Elem()
+	b := &configBinder{origins: map[string]configOrigin{}}
+	b.bindDefaults(root, "")
+	for _, file := range files {
+		m, err := readConfigFile(file)
+		if err != nil {
+			b.errs = append(b.errs, err)
+			continue
+		}
+		b.bindMap(root, m, "", file)
+	}
+	b.bindEnv(root, "")
+	if len(b.errs) > 0 {
+		return nil, fmt.Errorf("配置加载失败: %w", errors.Join(b.errs...))
+	}
+	return b.origins, validateConfig(cfg)
+}
+
+func readConfigFile(file string) (map[string]any, error) {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return nil, fmt.Errorf("读取配置文件失败: %w", err)
+	}
+	m := map[string]any{}
+	switch ext := strings.ToLower(filepath.Ext(file)); ext {
+	case ".yaml", ".yml":
+		err = yaml.Unmarshal(data, &m)
+	case ".toml":
+		err = toml.Unmarshal(data, &m)
+	case ".json":
+		err = json.Unmarshal(data, &m)
+	default:
+		return nil, fmt.Errorf("不支持的配置文件格式 %s: %s", ext, file)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("解析配置文件 %s 失败: %w", file, err)
+	}
+	return m, nil
+}
+
+// configField 遍历结构体可导出字段时的信息，inline 表示没有 conf tag 的匿名嵌入，字段直接展开到上一层。
+type configField struct {
+	value  reflect.Value
+	field  reflect.StructField
+	key    string
+	inline bool
+}
+
+func configFields(v reflect.Value) []configField {
+	var fields []configField
+	t := v.Type()
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if !f.IsExported() {
+			continue
+		}
+		key, hasKey := f.Tag.Lookup("conf")
+		if key == "-" {
+			continue
+		}
+		if !hasKey {
+			key = f.Name
+		}
+		fields = append(fields, configField{
+			value:  v.Field(i),
+			field:  f,
+			key:    key,
+			inline: f.Anonymous && !hasKey && f.Type.Kind() == reflect.Struct,
+		})
+	}
+	return fields
+}
+
+// isSection 判断字段是否作为嵌套配置段递归处理，而不是当作单个值解析。
+func isSection(v reflect.Value) bool {
+	if v.Kind() != reflect.Struct {
+		return false
+	}
+	_, ok := v.Addr().Interface().(encoding.TextUnmarshaler)
+	return !ok
+}
+
+func joinPath(prefix, key string) string {
+	if prefix == "" {
+		return key
+	}
+	return prefix + "." + key
+}
+
+// path 返回字段的配置路径，inline 字段沿用上一层路径。
+func (f configField) path(prefix string) string {
+	if f.inline {
+		return prefix
+	}
+	return joinPath(prefix, f.key)
+}
+
+func (b *configBinder) bindDefaults(v reflect.Value, prefix string) {
+	for _, f := range configFields(v) {
+		path := f.path(prefix)
+		if isSection(f.value) {
+			b.bindDefaults(f.value, path)
+			continue
+		}
+		def, ok := f.field.Tag.Lookup("default")
+		if !ok || !f.value.IsZero() {
+			continue
+		}
+		if err := setString(f.value, def); err != nil {
+			b.errs = append(b.errs, fmt.Errorf("%s 默认值 %q 无效: %w", path, def, err))
+			continue
+		}
+		b.origins[path] = configOrigin{source: "default"}
+	}
+}
+
+func (b *configBinder) bindMap(v reflect.Value, m map[string]any, prefix, file string) {
+	for _, f := range configFields(v) {
+		if f.inline {
+			b.bindMap(f.value, m, prefix, file)
+			continue
+		}
+		raw, ok := lookupKey(m, f.key)
+		if !ok || raw == nil {
+			continue
+		}
+		path := f.path(prefix)
+		if isSection(f.value) {
+			sub, ok := raw.(map[string]any)
+			if !ok {
+				b.errs = append(b.errs, fmt.Errorf("%s 应为对象，实际为 %T", path, raw))
+				continue
+			}
+			b.bindMap(f.value, sub, path, file)
+			continue
+		}
+		if err := setRaw(f.value, raw); err != nil {
+			b.errs = append(b.errs, fmt.Errorf("%s: %w", path, err))
+			continue
+		}
+		b.origins[path] = configOrigin{source: "file", from: file}
+	}
+}
+
+func lookupKey(m map[string]any, key string) (any, bool) {
+	if v, ok := m[key]; ok {
+		return v, true
+	}
+	for k, v := range m {
+		if strings.EqualFold(k, key) {
+			return v, true
+		}
+	}
+	return nil, false
+}
+
+// bindEnv 用 env tag 指定的环境变量覆盖字段；NAME 未设置时读取 NAME_FILE 指向的文件（Docker / K8s secret 约定），
+// 去掉末尾换行后作为取值。
+func (b *configBinder) bindEnv(v reflect.Value, prefix string) {
+	for _, f := range configFields(v) {
+		path := f.path(prefix)
+		if isSection(f.value) {
+			b.bindEnv(f.value, path)
+			continue
+		}
+		if envPrefix := f.field.Tag.Get("envPrefix"); envPrefix != "" && f.value.Kind() == reflect.Map {
+			b.bindEnvPrefix(f.value, path, envPrefix)
+			continue
+		}
+		name := f.field.Tag.Get("env")
+		if name == "" {
+			continue
+		}
+		s, from, err := lookupEnv(name)
+		if err != nil {
+			b.errs = append(b.errs, err)
+			continue
+		}
+		if s == "" {
+			continue
+		}
+		if err := setString(f.value, s); err != nil {
+			// 值可能是密钥，错误信息里不回显。
+			if from != name || isSecretField(f.field, f.key) {
+				b.errs = append(b.errs, fmt.Errorf("环境变量 %s 无效: %w", from, err))
+			} else {
+				b.errs = append(b.errs, fmt.Errorf("环境变量 %s=%q 无效: %w", name, s, err))
+			}
+			continue
+		}
+		b.origins[path] = configOrigin{source: "env", from: from}
+	}
+}
+
+// bindEnvPrefix 把所有以 envPrefix 开头的环境变量绑定到 map[string]T，键为去掉前缀后的小写名字，
+// 如 envPrefix 为 DATABASE_DSN_ 时 DATABASE_DSN_REPORTING 绑定为键 "reporting"。同样支持 _FILE 后缀。
+func (b *configBinder) bindEnvPrefix(v reflect.Value, path, envPrefix string) {
+	seen := map[string]bool{}
+	for _, kv := range os.Environ() {
+		key, _, _ := strings.Cut(kv, "=")
+		suffix, ok := strings.CutPrefix(key, envPrefix)
+		if !ok || suffix == "" || suffix == "FILE" {
+			continue
+		}
+		suffix = strings.TrimSuffix(suffix, "_FILE")
+		if seen[suffix] {
+			continue
+		}
+		seen[suffix] = true
+		s, from, err := lookupEnv(envPrefix + suffix)
+		if err != nil {
+			b.errs = append(b.errs, err)
+			continue
+		}
+		if s == "" {
+			continue
+		}
+		elem := reflect.New(v.Type().Elem()).Elem()
+		if err := setString(elem, s); err != nil {
+			b.errs = append(b.errs, fmt.Errorf("环境变量 %s 无效: %w", from, err))
+			continue
+		}
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		name := strings.ToLower(suffix)
+		v.SetMapIndex(reflect.ValueOf(name).Convert(v.Type().Key()), elem)
+		b.origins[joinPath(path, name)] = configOrigin{source: "env", from: from}
+	}
+}
+
+// lookupEnv 读取 name，未设置时读取 name_FILE 指向的文件，返回取值与实际来源的变量名。
+func lookupEnv(name string) (value, from string, err error) {
+	if s := os.Getenv(name); s != "" {
+		return s, name, nil
+	}
+	fileEnv := name + "_FILE"
+	path := os.Getenv(fileEnv)
+	if path == "" {
+		return "", "", nil
+	}
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return "", "", fmt.Errorf("环境变量 %s 指向的文件读取失败: %w", fileEnv, err)
+	}
+	return strings.TrimRight(string(data), "\r\n"), fileEnv, nil
+}
+
+var (
+	durationType = reflect.TypeFor[time.Duration]()
+	fileModeType = reflect.TypeFor[os.FileMode]()
+)
+
+// setString 按字段类型解析字符串：encoding.TextUnmarshaler、time.Duration（"5s"）、
+// os.FileMode（八进制 "0660"）、基础类型，切片按逗号分隔。
+func setString(v reflect.Value, s string) error {
+	if u, ok := v.Addr().Interface().(encoding.TextUnmarshaler); ok {
+		return u.UnmarshalText([]byte(s))
+	}
+	switch v.Type() {
+	case durationType:
+		d, err := time.ParseDuration(s)
+		if err != nil {
+			return err
+		}
+		v.SetInt(int64(d))
+		return nil
+	case fileModeType:
+		m, err := strconv.ParseUint(s, 8, 32)
+		if err != nil {
+			return err
+		}
+		v.SetUint(m)
+		return nil
+	}
+	switch v.Kind() {
+	case reflect.String:
+		v.SetString(s)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		v.SetBool(b)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		n, err := strconv.ParseFloat(s, v.Type().Bits())
+		if err != nil {
+			return err
+		}
+		v.SetFloat(n)
+	case reflect.Slice:
+		parts := splitList(s)
+		sl := reflect.MakeSlice(v.Type(), len(parts), len(parts))
+		for i, p := range parts {
+			if err := setString(sl.Index(i), p); err != nil {
+				return err
+			}
+		}
+		v.Set(sl)
+	default:
+		return fmt.Errorf("不支持的字段类型 %s", v.Type())
+	}
+	return nil
+}
+
+// setRaw 把配置文件解析出的值（string / 数字 / bool / []any）写入字段。
+func setRaw(v reflect.Value, raw any) error {
+	switch r := raw.(type) {
+	case string:
+		return setString(v, r)
+	case []any:
+		if v.Kind() != reflect.Slice {
+			return fmt.Errorf("应为 %s，实际为数组", v.Type())
+		}
+		sl := reflect.MakeSlice(v.Type(), len(r), len(r))
+		for i, item := range r {
+			if err := setRaw(sl.Index(i), item); err != nil {
+				return fmt.Errorf("[%d]: %w", i, err)
+			}
+		}
+		v.Set(sl)
+		return nil
+	case map[string]any:
+		if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+			return fmt.Errorf("应为 %s，实际为对象", v.Type())
+		}
+		// 多个文件的同一个 map 合并，后者覆盖同名键。
+		if v.IsNil() {
+			v.Set(reflect.MakeMap(v.Type()))
+		}
+		for k, item := range r {
+			elem := reflect.New(v.Type().Elem()).Elem()
+			if err := setRaw(elem, item); err != nil {
+				return fmt.Errorf("%s: %w", k, err)
+			}
+			v.SetMapIndex(reflect.ValueOf(k).Convert(v.Type().Key()), elem)
+		}
+		return nil
+	}
+	if v.Type() == durationType {
+		return fmt.Errorf("应为时长字符串（如 \"5s\"），实际为 %v", raw)
+	}
+	rv := reflect.ValueOf(raw)
+	switch {
+	case v.Kind() == reflect.Bool && rv.Kind() == reflect.Bool:
+		v.SetBool(rv.Bool())
+		return nil
+	case isNumber(v.Kind()) && isNumber(rv.Kind()):
+		if f, ok := raw.(float64); ok && !isFloat(v.Kind()) && f != float64(int64(f)) {
+			return fmt.Errorf("应为整数，实际为 %v", f)
+		}
+		if v.Type() == fileModeType {
+			// 数字形式的文件权限按字面值理解：YAML 的 0660 已解析为八进制，TOML / JSON 请写成字符串。
+			v.SetUint(uint64(rv.Convert(reflect.TypeFor[uint64]()).Uint()))
+			return nil
+		}
+		v.Set(rv.Convert(v.Type()))
+		return nil
+	}
+	return setString(v, fmt.Sprint(raw))
+}
+
+func isNumber(k reflect.Kind) bool {
+	return k >= reflect.Int && k <= reflect.Float64
+}
+
+func isFloat(k reflect.Kind) bool {
+	return k == reflect.Float32 || k == reflect.Float64
+}
+
+func splitList(s string) []string {
+	var res []string
+	for _, p := range strings.Split(s, ",") {
+		if p = strings.TrimSpace(p); p != "" {
+			res = append(res, p)
+		}
+	}
+	return res
+}
+
+var configValidator = func() *validator.Validate {
+	v := validator.New(validator.WithRequiredStructEnabled())
+	// 校验错误里使用配置路径（conf tag）而不是 Go 字段名。
+	v.RegisterTagNameFunc(func(f reflect.StructField) string {
+		if key := f.Tag.Get("conf"); key != "" && key != "-" {
+			return key
+		}
+		return f.Name
+	})
+	return v
+}()
+
+func validateConfig(cfg any) error {
+	err := configValidator.Struct(cfg)
+	var verrs validator.ValidationErrors
+	if !errors.As(err, &verrs) {
+		return err
+	}
+	errs := make([]error, 0, len(verrs))
+	for _, fe := range verrs {
+		// Namespace 以结构体类型名开头，去掉后即配置路径。
+		_, path, _ := strings.Cut(fe.Namespace(), ".")
+		rule := fe.Tag()
+		if fe.Param() != "" {
+			rule += "=" + fe.Param()
+		}
+		errs = append(errs, fmt.Errorf("%s=%v 不满足 %s", path, invalidValue(reflect.TypeOf(cfg), fe), rule))
+	}
+	return fmt.Errorf("配置校验失败: %w", errors.Join(errs...))
+}
+
+// invalidValue 校验错误中展示的值，与 ConfigDump 一样打码：密钥字段整体打码，DSN 去掉密码。
+func invalidValue(t reflect.Type, fe validator.FieldError) any {
+	v := reflect.ValueOf(fe.Value())
+	if !v.IsValid() {
+		return fe.Value()
+	}
+	// StructNamespace 形如 Type.Database.Replicas[0]，逐级找到出错的字段。
+	_, ns, _ := strings.Cut(fe.StructNamespace(), ".")
+	var f reflect.StructField
+	for name := range strings.SplitSeq(ns, ".") {
+		name, _, _ = strings.Cut(name, "[")
+		for t.Kind() == reflect.Pointer || t.Kind() == reflect.Slice || t.Kind() == reflect.Map {
+			t = t.Elem()
+		}
+		if t.Kind() != reflect.Struct {
+			return redacted
+		}
+		sf, ok := t.FieldByName(name)
+		if !ok {
+			return redacted
+		}
+		f, t = sf, sf.Type
+	}
+	return dumpValue(configField{field: f, key: fe.Field()}, v)
+}
@@ -0,0 +1,143 @@
+package gowk
+
+import (
+	"crypto/hmac"
+	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"reflect"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// pageCursor 游标内容：Key 为边界行各排序字段的值（JSON），Sort 为排序方式的摘要，
+// 排序字段或方向与签发时不同的游标视为无效；Prev 为 true 时取边界之前的一页。
+type pageCursor struct {
+	Prev bool              `json:"p,omitempty"`
+	Sort string            `json:"s"`
+	Key  []json.RawMessage `json:"k"`
+}
+
+// cursorPagination 是否注册了游标分页的接口，RunContext 据此要求配置 PAGE_CURSOR_SECRET。
+var cursorPagination atomic.Bool
+
+// checkCursorSecret 注册了游标分页接口却没有配置密钥时返回 error：各实例、重启或零停机重启后的进程
+// 会用不同的随机密钥签名，客户端拿到的游标会间歇性地“cursor 无效”。
+func checkCursorSecret() error {
+	if cursorPagination.Load() && conf().Page.CursorSecret == "" {
+		return errors.New("已注册游标分页接口，需要配置 PAGE_CURSOR_SECRET（多实例共用同一个值）")
+	}
+	return nil
+}
+
+var cursorRandomKey = sync.OnceValue(func() []byte {
+	key := make([]byte, 32)
+	if _, err := rand.Read(key); err != nil {
+		panic("crypto/rand 不可用: " + err.Error())
+	}
+	return key
+})
+
+// cursorKey 游标签名密钥，PAGE_CURSOR_SECRET 为空时为进程内随机密钥（不经 RunContext 启动时，如测试或嵌入使用）。
+func cursorKey() []byte {
+	if secret := conf().Page.CursorSecret; secret != "" {
+		return []byte(secret)
+	}
+	return cursorRandomKey()
+}
+
+func cursorSign(payload string) []byte {
+	mac := hmac.New(sha256.New, cursorKey())
+	mac.Write([]byte(payload))
+	return mac.Sum(nil)
+}
+
+// sortDigest 排序方式（表、字段与方向）的摘要，写入游标防止换了 sort 之后继续使用旧游标。
+func sortDigest(table string, keys []sortKey) string {
+	var b strings.Builder
+	b.WriteString(table)
+	for _, k := range keys {
+		b.WriteString("|" + k.col.name + ":" + strconv.FormatBool(k.desc))
+	}
+	sum := sha256.Sum256([]byte(b.String()))
+	return base64.RawURLEncoding.EncodeToString(sum[:6])
+}
+
+// encodeCursor 以 v 中 keys 各字段的值生成签名后的游标：base64url(JSON).base64url(HMAC-SHA256)。
+func encodeCursor(table string, keys []sortKey, v reflect.Value, prev bool) (string, error) {
+	c := pageCursor{Prev: prev, Sort: sortDigest(table, keys), Key: make([]json.RawMessage, len(keys))}
+	for i, k := range keys {
+		raw, err := json.Marshal(v.FieldByIndex(k.col.index).Interface())
+		if err != nil {
+			return "", err
+		}
+		c.Key[i] = raw
+	}
+	data, err := json.Marshal(c)
+	if err != nil {
+		return "", err
+	}
+	payload := base64.RawURLEncoding.EncodeToString(data)
+	return payload + "." + base64.RawURLEncoding.EncodeToString(cursorSign(payload)), nil
+}
+
+// decodeCursor 校验签名与排序方式，并把 Key 解析为各排序字段类型的值；无效时返回 ERR_PARAM 类错误。
+func decodeCursor(cursor, table string, keys []sortKey) (prev bool, values []any, err error) {
+	invalid := queryParamError("cursor 无效")
+	payload, sig, ok := strings.Cut(cursor, ".")
+	if !ok {
+		return false, nil, invalid
+	}
+	mac, err := base64.RawURLEncoding.DecodeString(sig)
+	if err != nil || subtle.ConstantTimeCompare(mac, cursorSign(payload)) != 1 {
+		return false, nil, invalid
+	}
+	data, err := base64.RawURLEncoding.DecodeString(payload)
+	if err != nil {
+		return false, nil, invalid
+	}
+	var c pageCursor
+	if err := json.Unmarshal(data, &c); err != nil || c.Sort != sortDigest(table, keys) || len(c.Key) != len(keys) {
+		return false, nil, queryParamError("cursor 与当前的排序方式不匹配")
+	}
+	values = make([]any, len(keys))
+	for i, k := range keys {
+		v := reflect.New(k.col.typ)
+		if err := json.Unmarshal(c.Key[i], v.Interface()); err != nil {
+			return false, nil, invalid
+		}
+		values[i] = v.Elem().Interface()
+	}
+	return c.Prev, values, nil
+}
+
+// keysetCondition 生成“位于游标之后”的条件（reverse 为 true 时为之前），各字段方向可以不同，
+// 因此展开为 (a > $1) OR (a = $1 AND b > $2) ...，而不是行比较 (a, b) > ($1, $2)。
+func keysetCondition(keys []sortKey, values []any, reverse bool, args []any) (string, []any) {
+	placeholders := make([]string, len(keys))
+	for i, v := range values {
+		args = append(args, v)
+		placeholders[i] = "$" + strconv.Itoa(len(args))
+	}
+	ors := make([]string, len(keys))
+	for i, k := range keys {
+		var ands []string
+		for j := range i {
+			ands = append(ands, pgx.Identifier{keys[j].col.name}.Sanitize()+" = "+placeholders[j])
+		}
+		op := ">"
+		if k.desc != reverse {
+			op = "<"
+		}
+		ands = append(ands, pgx.Identifier{k.col.name}.Sanitize()+" "+op+" "+placeholders[i])
+		ors[i] = "(" + strings.Join(ands, " AND ") + ")"
+	}
+	return "(" + strings.Join(ors, " OR ") + ")", args
+}
@@ -0,0 +1,122 @@
+package gowk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestCursor(t *testing.T) {
+	keepConfig(t)
+	updateConfig(func(c *Config) { c.Page.CursorSecret = "s1" })
+	meta, _ := metaOf[testMember]()
+	keys, err := meta.sortKeys([]Sort{{Field: "createdAt", Desc: true}})
+	if err != nil || len(keys) != 2 || keys[1].col != meta.pk {
+		t.Fatalf("keys = %+v %v", keys, err)
+	}
+	created := time.Date(2026, 1, 2, 3, 4, 5, 6000, time.UTC)
+	cursor, err := encodeCursor(meta.table, keys, reflect.ValueOf(testMember{ID: 7, CreatedAt: created}), true)
+	if err != nil {
+		t.Fatal(err)
+	}
+	prev, values, err := decodeCursor(cursor, meta.table, keys)
+	if err != nil || !prev || !reflect.DeepEqual(values, []any{created, int64(7)}) {
+		t.Fatalf("decode = %v %#v %v", prev, values, err)
+	}
+
+	isParamErr := func(err error) bool {
+		var code *ErrorCode
+		return errors.As(err, &code) && code.Code == ERR_PARAM.Code
+	}
+	ageKeys, _ := meta.sortKeys([]Sort{{Field: "age"}})
+	if _, _, err := decodeCursor(cursor, meta.table, ageKeys); !isParamErr(err) {
+		t.Errorf("cursor of another sort: %v", err)
+	}
+	if _, _, err := decodeCursor("x"+cursor, meta.table, keys); !isParamErr(err) {
+		t.Errorf("tampered cursor: %v", err)
+	}
+	updateConfig(func(c *Config) { c.Page.CursorSecret = "s2" })
+	if _, _, err := decodeCursor(cursor, meta.table, keys); !isParamErr(err) {
+		t.Errorf("cursor signed with another secret: %v", err)
+	}
+
+	cond, args := keysetCondition(keys, values, false, []any{"x"})
+	if cond != `(("created_at" < $2) OR ("created_at" = $2 AND "id" > $3))` || len(args) != 3 {
+		t.Fatalf("keyset = %s %v", cond, args)
+	}
+	cond, _ = keysetCondition(keys, values, true, nil)
+	if cond != `(("created_at" > $1) OR ("created_at" = $1 AND "id" < $2))` {
+		t.Fatalf("reverse keyset = %s", cond)
+	}
+	if order := orderByKeys(keys, true); order != ` ORDER BY "created_at", "id" DESC` {
+		t.Fatalf("reverse orderBy = %s", order)
+	}
+}
+
+// cursorBooks 在 memoryBooks 之上实现 CursorPager，只用于检查 Handler 的参数与链接。
+type cursorBooks struct {
+	*memoryBooks
+}
+
+func (c cursorBooks) CursorPage(page *CursorPage[testBook], q *testBook) (*CursorPage[testBook], error) {
+	page.Records = []*testBook{{ID: 1, Title: q.Title}}
+	page.NextCursor = "next" + page.Cursor
+	return page, nil
+}
+
+func TestHandlerCursorPage(t *testing.T) {
+	keepConfig(t)
+	t.Cleanup(func() { cursorPagination.Store(false) })
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(GlobalErrorHandler())
+	store := &memoryBooks{books: map[int64]testBook{}}
+	updateConfig(func(c *Config) {
+		c.Page.Modes = map[string]string{"cursor_books": "cursor", "bad_books": "cursor", "opt_books": "cursor"}
+	})
+	Resource[testBook](engine.Group("/cursor"), "/books", sharedService[testBook](cursorBooks{store}))
+	Resource[testBook](engine.Group("/plain"), "/books", sharedService[testBook](store))
+	// NewService 没有实现 CursorPager：Register 不调用工厂，第一次请求时才报错。
+	Resource[testBook](engine.Group("/bad"), "/books", sharedService[testBook](store))
+	// 显式设置 CursorService 时不要求 NewService 实现 CursorPager。
+	h := NewHandler(sharedService[testBook](store))
+	h.CursorService = func(*gin.Context) CursorPager[testBook] { return cursorBooks{store} }
+	h.Register(engine.Group("/opt"), "/books")
+
+	get := func(path string) (int, string) {
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w.Code, w.Body.String()
+	}
+	for _, prefix := range []string{"/cursor", "/opt"} {
+		code, body := get(prefix + "/books?size=1000&title=go&cursor=1")
+		if code != http.StatusOK || !strings.Contains(body, `"size":100`) || !strings.Contains(body, `"nextCursor":"next1"`) ||
+			!strings.Contains(body, `"next":"`+prefix+`/books?cursor=next1\u0026size=1000\u0026title=go"`) || strings.Contains(body, `"prev"`) {
+			t.Fatalf("cursor page: %d %s", code, body)
+		}
+	}
+	// 只用于输出的字段不从查询参数绑定。
+	if _, body := get("/cursor/books?Total=1&NextCursor=x&Next=x"); strings.Contains(body, `"total"`) || strings.Contains(body, `"next":"x"`) {
+		t.Fatalf("output fields bound from the query: %s", body)
+	}
+	if _, body := get("/bad/books"); !strings.Contains(body, errCursorUnsupported.Msg) || strings.Contains(body, "memoryBooks") {
+		t.Fatalf("cursor page without a CursorPager: %s", body)
+	}
+
+	if _, body := get("/plain/books"); !strings.Contains(body, `"pages"`) {
+		t.Fatalf("offset page: %s", body)
+	}
+	if err := checkCursorSecret(); err == nil || !strings.Contains(err.Error(), "PAGE_CURSOR_SECRET") {
+		t.Fatalf("cursor pagination without a secret should refuse to start: %v", err)
+	}
+	updateConfig(func(c *Config) { c.Page.CursorSecret = "s" })
+	if err := checkCursorSecret(); err != nil {
+		t.Fatal(err)
+	}
+}
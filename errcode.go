@@ -89,7 +89,8 @@ var (
 	OK  = NewErrorCode(0, "成功")
 	ERR = NewErrorCode(-1, "错误")
 
-	NOT_FOUND = &ErrorCode{Status: 404, Code: 404, Msg: "未找到"}
+	NOT_FOUND     = &ErrorCode{Status: 404, Code: 404, Msg: "未找到"}
+	ERR_FORBIDDEN = &ErrorCode{Status: 403, Code: 403, Msg: "无权限"}
 
 	ERR_AUTH     = newAuthErrorCode(401, "认证失败")
 	ERR_PARAM    = NewErrorCode(1401, "参数错误")
@@ -23,3 +23,9 @@ func Go(f func()) {
 		f()
 	}()
 }
+
+// GoroutinePoolStats 返回 Go() 协程池当前占用的槽位数与总容量。
+func GoroutinePoolStats() (inUse, size int) {
+	sem := goroutines.sem
+	return len(sem), cap(sem)
+}
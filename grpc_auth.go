@@ -0,0 +1,126 @@
+package gowk
+
+import (
+	"context"
+	"encoding/base64"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+)
+
+// grpcAlwaysPublic 无论是否配置白名单都跳过认证的方法，K8s gRPC 探针依赖它。
+var grpcAlwaysPublic = []string{"/grpc.health.v1.Health/*"}
+
+// UnaryCheckLoginInterceptor 是 CheckLogin 的 gRPC 版本：
+// 从 metadata 的 authorization 读取 Bearer token（经 TokenHandler 校验）或 Basic 凭据（经 SetBasicAuthValidator 校验），
+// 成功后写入 ctx，handler 中 LoginId(ctx) / TokenInfo(ctx) / TokenValue(ctx) 可直接使用。
+// publicMethods 为免认证的方法白名单，支持完整方法名 "/pkg.Service/Method" 与服务通配 "/pkg.Service/*"。
+func UnaryCheckLoginInterceptor(publicMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if grpcPublicMethod(info.FullMethod, publicMethods) {
+			return handler(ctx, req)
+		}
+		ctx, err := grpcCheckLogin(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamCheckLoginInterceptor 是 UnaryCheckLoginInterceptor 的流式版本。
+func StreamCheckLoginInterceptor(publicMethods ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if grpcPublicMethod(info.FullMethod, publicMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := grpcCheckLogin(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+// UnaryCheckClientInterceptor 是 CheckClient 的 gRPC 版本：按 SetClientKeyNames 配置的名字
+// 从 metadata 读取 API key（metadata key 不区分大小写），经 ClientHandler 校验后写入 ctx。
+func UnaryCheckClientInterceptor(publicMethods ...string) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		if grpcPublicMethod(info.FullMethod, publicMethods) {
+			return handler(ctx, req)
+		}
+		ctx, err := grpcCheckClient(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamCheckClientInterceptor 是 UnaryCheckClientInterceptor 的流式版本。
+func StreamCheckClientInterceptor(publicMethods ...string) grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if grpcPublicMethod(info.FullMethod, publicMethods) {
+			return handler(srv, ss)
+		}
+		ctx, err := grpcCheckClient(ss.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+func grpcPublicMethod(method string, publicMethods []string) bool {
+	for _, list := range [][]string{grpcAlwaysPublic, publicMethods} {
+		for _, p := range list {
+			if p == method {
+				return true
+			}
+			if prefix, ok := strings.CutSuffix(p, "*"); ok && strings.HasPrefix(method, prefix) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func grpcCheckLogin(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	authHeader := firstMetadata(md, AUTHORIZATION)
+	if tokenValue, ok := strings.CutPrefix(authHeader, "Bearer "); ok && tokenValue != "" {
+		token, err := _defaultTokenHandler.LoadToken(ctx, tokenValue)
+		if err == nil && token != nil {
+			ctx = context.WithValue(ctx, ContextTokenKey, token)
+			ctx = context.WithValue(ctx, ContextTokenValueKey, token.Value)
+			return context.WithValue(ctx, ContextLoginIdKey, token.LoginId), nil
+		}
+	}
+	if auth, ok := strings.CutPrefix(authHeader, "Basic "); ok && auth != "" {
+		decoded, err := base64.StdEncoding.DecodeString(auth)
+		if err == nil && _basicAuthValidator != nil && _basicAuthValidator(string(decoded)) {
+			return context.WithValue(ctx, ContextBasicAuthKey, string(decoded)), nil
+		}
+	}
+	return ctx, GrpcStatus(TokenError("Authentication required"))
+}
+
+func grpcCheckClient(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	var keyValue string
+	for _, name := range clientKeyNames() {
+		if keyValue = firstMetadata(md, name); keyValue != "" {
+			break
+		}
+	}
+	if keyValue == "" {
+		return ctx, GrpcStatus(ERR_AUTH)
+	}
+	client, err := _defaultClientHandler.LoadClient(ctx, keyValue)
+	if err != nil || client == nil {
+		return ctx, GrpcStatus(ERR_AUTH)
+	}
+	ctx = context.WithValue(ctx, ContextClientKey, client)
+	return context.WithValue(ctx, ContextLoginIdKey, client.LoginId), nil
+}
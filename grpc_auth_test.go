@@ -0,0 +1,66 @@
+package gowk
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGrpcCheckLogin(t *testing.T) {
+	ctx := context.Background()
+	if err := _defaultTokenHandler.StoreToken(ctx, "grpc-token", &Token{Value: "grpc-token", LoginId: 42}); err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryCheckLoginInterceptor("/test.Public/*")
+	var loginId int64
+	handler := func(ctx context.Context, req any) (any, error) {
+		loginId = LoginId(ctx)
+		return "ok", nil
+	}
+	call := func(method string, md metadata.MD) error {
+		_, err := interceptor(metadata.NewIncomingContext(ctx, md), nil, &grpc.UnaryServerInfo{FullMethod: method}, handler)
+		return err
+	}
+
+	if err := call("/test.Private/Get", metadata.Pairs("authorization", "Bearer grpc-token")); err != nil {
+		t.Fatal(err)
+	}
+	if loginId != 42 {
+		t.Fatalf("LoginId = %d, want 42", loginId)
+	}
+	if err := call("/test.Private/Get", metadata.Pairs("authorization", "Bearer bad")); status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("bad token: %v", err)
+	}
+	if err := call("/test.Public/Get", nil); err != nil {
+		t.Fatalf("public method should skip auth: %v", err)
+	}
+	if err := call("/grpc.health.v1.Health/Check", nil); err != nil {
+		t.Fatalf("health should skip auth: %v", err)
+	}
+}
+
+func TestGrpcCheckClient(t *testing.T) {
+	ctx := context.Background()
+	if err := StoreClient(ctx, "grpc-key", &Client{Key: "grpc-key", LoginId: 7}); err != nil {
+		t.Fatal(err)
+	}
+	interceptor := UnaryCheckClientInterceptor()
+	var loginId int64
+	_, err := interceptor(metadata.NewIncomingContext(ctx, metadata.Pairs("x-api-key", "grpc-key")), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/test.Private/Get"}, func(ctx context.Context, req any) (any, error) {
+			loginId = LoginId(ctx)
+			return nil, nil
+		})
+	if err != nil || loginId != 7 {
+		t.Fatalf("err=%v loginId=%d", err, loginId)
+	}
+	_, err = interceptor(metadata.NewIncomingContext(ctx, nil), nil,
+		&grpc.UnaryServerInfo{FullMethod: "/test.Private/Get"}, func(ctx context.Context, req any) (any, error) { return nil, nil })
+	if status.Code(err) != codes.Unauthenticated {
+		t.Fatalf("missing key: %v", err)
+	}
+}
@@ -0,0 +1,222 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+// 以下拦截器与 gin 中间件一一对应：
+//   - RequestMiddleware  → UnaryRequestInterceptor / StreamRequestInterceptor
+//   - GlobalErrorHandler → UnaryErrorInterceptor / StreamErrorInterceptor
+//   - Recover            → UnaryRecoverInterceptor / StreamRecoverInterceptor
+//
+// NewGrpcServer 默认按 Request → Error → Recover 的顺序安装。
+
+// grpcDefaultInterceptors 返回 NewGrpcServer 默认安装的拦截器链。
+func grpcDefaultInterceptors() []grpc.ServerOption {
+	return []grpc.ServerOption{
+		grpc.ChainUnaryInterceptor(UnaryRequestInterceptor(), UnaryErrorInterceptor(), UnaryRecoverInterceptor()),
+		grpc.ChainStreamInterceptor(StreamRequestInterceptor(), StreamErrorInterceptor(), StreamRecoverInterceptor()),
+	}
+}
+
+// UnaryRequestInterceptor 从 metadata 读取 / 生成 trace_id、span_id 写入 ctx，并打印 start / end 日志。
+func UnaryRequestInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		ctx = grpcRequestInLog(ctx, info.FullMethod)
+		resp, err := handler(ctx, req)
+		grpcRequestOutLog(ctx, err)
+		return resp, err
+	}
+}
+
+// StreamRequestInterceptor 是 UnaryRequestInterceptor 的流式版本，end 日志在流结束时打印。
+func StreamRequestInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := grpcRequestInLog(ss.Context(), info.FullMethod)
+		err := handler(srv, &contextServerStream{ServerStream: ss, ctx: ctx})
+		grpcRequestOutLog(ctx, err)
+		return err
+	}
+}
+
+// UnaryErrorInterceptor 把 handler 返回的 *ErrorCode 等错误转换为 gRPC status，见 GrpcStatus。
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (any, error) {
+		resp, err := handler(ctx, req)
+		return resp, GrpcStatus(err)
+	}
+}
+
+// StreamErrorInterceptor 是 UnaryErrorInterceptor 的流式版本。
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		return GrpcStatus(handler(srv, ss))
+	}
+}
+
+// UnaryRecoverInterceptor 捕获 handler 中的 panic（含 Panic(*ErrorCode)）并转换为 error，
+// 处理方式与 Recover 一致。
+func UnaryRecoverInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req any, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (resp any, err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = grpcRecovered(ctx, r)
+			}
+		}()
+		return handler(ctx, req)
+	}
+}
+
+// StreamRecoverInterceptor 是 UnaryRecoverInterceptor 的流式版本。
+func StreamRecoverInterceptor() grpc.StreamServerInterceptor {
+	return func(srv any, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) (err error) {
+		defer func() {
+			if r := recover(); r != nil {
+				err = grpcRecovered(ss.Context(), r)
+			}
+		}()
+		return handler(srv, ss)
+	}
+}
+
+// grpcRecovered 把 panic 转换为 error。与 Recover 一致，Panic(OK) 视为正常结束，返回 nil；
+// 一元调用此时沿用 handler 已经得到的 resp（handler 没有返回时为 nil）。
+func grpcRecovered(ctx context.Context, r any) error {
+	switch tp := r.(type) {
+	case *ErrorCode:
+		if tp.Code == OK.Code {
+			return nil
+		}
+		return tp
+	case runtime.Error:
+		slog.ErrorContext(ctx, tp.Error())
+		return Error(tp)
+	default:
+		slog.ErrorContext(ctx, "recover", "type", fmt.Sprintf("%T", r), "value", r)
+		return NewError(fmt.Sprintf("%v", r))
+	}
+}
+
+// GrpcStatus 把 error 转换为 gRPC status error：
+//   - 已经是 status error 的原样返回；
+//   - *ErrorCode 按 Status（HTTP 状态码）映射 gRPC code，Msg 作为 message，
+//     Code 放入 errdetails.ErrorInfo（Domain "gowk"，Reason 与 Metadata["code"] 为业务码）；
+//   - context 超时 / 取消映射为 DeadlineExceeded / Canceled；
+//   - 其他错误为 Unknown。
+func GrpcStatus(err error) error {
+	if err == nil {
+		return nil
+	}
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	var ec *ErrorCode
+	if errors.As(err, &ec) {
+		st := status.New(grpcCode(ec.Status), ec.Msg)
+		code := strconv.Itoa(ec.Code)
+		if withDetails, derr := st.WithDetails(&errdetails.ErrorInfo{
+			Reason:   code,
+			Domain:   "gowk",
+			Metadata: map[string]string{"code": code},
+		}); derr == nil {
+			st = withDetails
+		}
+		return st.Err()
+	}
+	if errors.Is(err, context.DeadlineExceeded) || errors.Is(err, context.Canceled) {
+		return status.FromContextError(err).Err()
+	}
+	return status.Error(codes.Unknown, err.Error())
+}
+
+// grpcCode 把 ErrorCode.Status（HTTP 状态码）映射为 gRPC code；Status 为 0 的业务错误为 Unknown。
+func grpcCode(httpStatus int) codes.Code {
+	switch httpStatus {
+	case http.StatusBadRequest:
+		return codes.InvalidArgument
+	case http.StatusUnauthorized:
+		return codes.Unauthenticated
+	case http.StatusForbidden:
+		return codes.PermissionDenied
+	case http.StatusNotFound:
+		return codes.NotFound
+	case http.StatusConflict:
+		return codes.AlreadyExists
+	case http.StatusTooManyRequests:
+		return codes.ResourceExhausted
+	case http.StatusNotImplemented:
+		return codes.Unimplemented
+	case http.StatusServiceUnavailable:
+		return codes.Unavailable
+	case http.StatusGatewayTimeout:
+		return codes.DeadlineExceeded
+	case http.StatusInternalServerError:
+		return codes.Internal
+	}
+	return codes.Unknown
+}
+
+func grpcRequestInLog(ctx context.Context, method string) context.Context {
+	md, _ := metadata.FromIncomingContext(ctx)
+	traceId := firstMetadata(md, TRACE_ID)
+	if traceId == "" {
+		traceId = uuid.NewString()
+	}
+	ctx = context.WithValue(ctx, START_TIME, time.Now())
+	ctx = context.WithValue(ctx, TRACE_ID, traceId)
+	if pspanId := firstMetadata(md, SPAN_ID); pspanId != "" {
+		ctx = context.WithValue(ctx, PSPAN_ID, pspanId)
+	}
+	ctx = context.WithValue(ctx, SPAN_ID, uuid.NewString())
+
+	// 只记录非敏感信息，不记录 metadata（含 authorization）和请求体
+	var ip string
+	if p, ok := peer.FromContext(ctx); ok && p.Addr != nil {
+		ip = p.Addr.String()
+	}
+	slog.InfoContext(ctx, "start",
+		"ip", ip,
+		"method", method,
+	)
+	return ctx
+}
+
+func grpcRequestOutLog(ctx context.Context, err error) {
+	startTime, _ := ctx.Value(START_TIME).(time.Time)
+	slog.InfoContext(ctx, "end",
+		"code", status.Code(err).String(),
+		"usedTime", time.Since(startTime).Milliseconds(),
+	)
+}
+
+func firstMetadata(md metadata.MD, key string) string {
+	if v := md.Get(key); len(v) > 0 {
+		return v[0]
+	}
+	return ""
+}
+
+// contextServerStream 替换 ServerStream 的 Context，使 handler 能拿到拦截器写入的值。
+type contextServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextServerStream) Context() context.Context {
+	return s.ctx
+}
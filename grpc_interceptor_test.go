@@ -0,0 +1,80 @@
+package gowk
+
+import (
+	"context"
+	"testing"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+func TestGrpcInterceptors(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/test.Service/Call"}
+	chain := func(handler grpc.UnaryHandler) (any, error) {
+		ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(TRACE_ID, "trace-1", SPAN_ID, "span-parent"))
+		return UnaryRequestInterceptor()(ctx, nil, info, func(ctx context.Context, req any) (any, error) {
+			return UnaryErrorInterceptor()(ctx, req, info, func(ctx context.Context, req any) (any, error) {
+				return UnaryRecoverInterceptor()(ctx, req, info, handler)
+			})
+		})
+	}
+
+	_, err := chain(func(ctx context.Context, req any) (any, error) {
+		if ctx.Value(TRACE_ID) != "trace-1" || ctx.Value(PSPAN_ID) != "span-parent" || ctx.Value(SPAN_ID) == nil {
+			t.Errorf("trace not propagated: trace=%v pspan=%v", ctx.Value(TRACE_ID), ctx.Value(PSPAN_ID))
+		}
+		Panic(ERR_AUTH)
+		return nil, nil
+	})
+	st := status.Convert(err)
+	if st.Code() != codes.Unauthenticated || st.Message() != ERR_AUTH.Msg {
+		t.Fatalf("status = %v %q", st.Code(), st.Message())
+	}
+	details := st.Details()
+	if len(details) != 1 || details[0].(*errdetails.ErrorInfo).Metadata["code"] != "401" {
+		t.Fatalf("error code not preserved: %v", details)
+	}
+
+	_, err = chain(func(ctx context.Context, req any) (any, error) {
+		var m map[string]int
+		m["boom"] = 1
+		return nil, nil
+	})
+	if status.Code(err) != codes.Unknown {
+		t.Fatalf("runtime panic: code = %v", status.Code(err))
+	}
+
+	_, err = chain(func(ctx context.Context, req any) (any, error) {
+		Panic(OK)
+		return nil, nil
+	})
+	if err != nil {
+		t.Fatalf("Panic(OK) should not be an error: %v", err)
+	}
+	err = StreamRecoverInterceptor()(nil, &grpcTestStream{ctx: context.Background()}, &grpc.StreamServerInfo{}, func(any, grpc.ServerStream) error {
+		Panic(OK)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("stream Panic(OK) should not be an error: %v", err)
+	}
+
+	_, err = chain(func(ctx context.Context, req any) (any, error) {
+		return nil, ERR_PARAM
+	})
+	st = status.Convert(err)
+	if st.Message() != ERR_PARAM.Msg || st.Details()[0].(*errdetails.ErrorInfo).Reason != "1401" {
+		t.Fatalf("business error not mapped: %v", st)
+	}
+}
+
+// grpcTestStream 只实现 Context 的 grpc.ServerStream。
+type grpcTestStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *grpcTestStream) Context() context.Context { return s.ctx }
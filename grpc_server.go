@@ -8,48 +8,141 @@ import (
 	"time"
 
 	"google.golang.org/grpc"
+	grpchealth "google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/reflection"
 )
 
 type GrpcServer struct {
 	Server *grpc.Server
+	// Health 为标准 grpc.health.v1 服务，ServerRun 后按 HEALTH_CHECK_INTERVAL 与 CheckHealth 同步。
+	// 服务名 "" 表示整体就绪；各依赖与自定义检查以其名字作为服务名。
+	Health *grpchealth.Server
+
+	healthCancel context.CancelFunc
+	serveErr     chan error
+	initErr      error
 }
 
-func NewGrpcServer() *GrpcServer {
-	s := grpc.NewServer()
+// NewGrpcServer 创建 gRPC Server，默认安装与 gin 中间件对应的拦截器（trace 日志、错误转换、panic 恢复），
+// 并注册 reflection 与 health 服务。
+// SetTLS / TLS_* 配置了证书时默认启用 TLS（单端口模式下 TLS 由共享端口终止，这里不再叠加）；
+// opts 追加在默认选项之后，可覆盖（例如 GrpcServerTLS）；其中的拦截器排在默认拦截器之后执行。
+// 证书加载失败不在这里 panic，而是推迟到 ServerRun 返回 error。
+func NewGrpcServer(opts ...grpc.ServerOption) *GrpcServer {
+	var initErr error
+	serverOpts := grpcDefaultInterceptors()
+	if tlsOpts := tlsOptions(); tlsOpts.enabled() && !singlePort() {
+		creds, err := GrpcServerTLS(tlsOpts)
+		if err != nil {
+			initErr = fmt.Errorf("gRPC TLS 配置失败: %w", err)
+		} else {
+			serverOpts = append(serverOpts, creds)
+		}
+	}
+	serverOpts = append(serverOpts, opts...)
+	s := grpc.NewServer(serverOpts...)
 	reflection.Register(s)
-	return &GrpcServer{Server: s}
+	h := grpchealth.NewServer()
+	healthpb.RegisterHealthServer(s, h)
+	return &GrpcServer{Server: s, Health: h, initErr: initErr}
 }
 
 // ServerRun 同步绑定端口并在后台 Serve。
 // GRPC_SERVER_ADDR 未配置视为"未启用"，返回 nil（不是错误）；
 // 已配置但监听失败，返回 error 交由调用方 fail-fast；
-// Serve 阶段的错误只打日志。
+// Serve 阶段的错误打日志并投递到 serveErrors()。
 func (s *GrpcServer) ServerRun() error {
 	if !HasGRPC() {
 		slog.Info("GRPC_SERVER_ADDR 未配置，跳过 gRPC 启动")
 		return nil
 	}
+	if s.initErr != nil {
+		return s.initErr
+	}
 	if s.Server == nil {
 		s.Server = grpc.NewServer()
 	}
-	lis, err := net.Listen("tcp", grpcServerAddr)
+	lis, err := listen(conf().Server.GRPCAddr)
 	if err != nil {
-		return fmt.Errorf("gRPC 监听失败 addr=%s: %w", grpcServerAddr, err)
+		return fmt.Errorf("gRPC 监听失败 addr=%s: %w", conf().Server.GRPCAddr, err)
+	}
+	s.serve(lis)
+	return nil
+}
+
+// serve 在后台对已绑定的 lis 执行 Serve，并启动 health 状态同步。
+func (s *GrpcServer) serve(lis net.Listener) {
+	if s.Server == nil {
+		s.Server = grpc.NewServer()
 	}
 	slog.Info("gRPC server running", "addr", lis.Addr().String())
+	s.serveErr = make(chan error, 1)
 	go func() {
 		if err := s.Server.Serve(lis); err != nil {
 			slog.Error("gRPC server serve failed", "addr", lis.Addr().String(), "err", err)
+			s.serveErr <- fmt.Errorf("gRPC serve 失败 addr=%s: %w", lis.Addr().String(), err)
 		}
 	}()
-	return nil
+	if s.Health != nil {
+		ctx, cancel := context.WithCancel(context.Background())
+		s.healthCancel = cancel
+		go s.syncHealth(ctx)
+	}
+}
+
+// serveErrors 返回 Serve 阶段的异常；未启动（含 GRPC_SERVER_ADDR 未配置）时为 nil channel。
+func (s *GrpcServer) serveErrors() <-chan error {
+	return s.serveErr
+}
+
+// syncHealth 周期把 CheckHealth 的结果写入 grpc.health.v1 服务。
+func (s *GrpcServer) syncHealth(ctx context.Context) {
+	interval := conf().Health.Interval
+	if interval <= 0 {
+		interval = 10 * time.Second
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		report := CheckHealth(ctx)
+		if ctx.Err() != nil {
+			return
+		}
+		s.Health.SetServingStatus("", servingStatus(report.Ready))
+		for _, d := range report.Dependencies {
+			s.Health.SetServingStatus(d.Name, servingStatus(d.State == HealthDisabled || d.State == HealthReady))
+		}
+		for _, c := range report.Checks {
+			s.Health.SetServingStatus(c.Name, servingStatus(c.Healthy))
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func servingStatus(ok bool) healthpb.HealthCheckResponse_ServingStatus {
+	if ok {
+		return healthpb.HealthCheckResponse_SERVING
+	}
+	return healthpb.HealthCheckResponse_NOT_SERVING
 }
 
 func (s *GrpcServer) ServerStop() {
 	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
 	defer cancel()
 
+	// 先把健康状态置为 NOT_SERVING，让负载均衡尽早摘流。
+	if s.healthCancel != nil {
+		s.healthCancel()
+	}
+	if s.Health != nil {
+		s.Health.Shutdown()
+	}
+
 	done := make(chan struct{})
 	go func() {
 		s.Server.GracefulStop()
@@ -1,15 +1,165 @@
 package gowk
 
-import "github.com/gin-gonic/gin"
+import (
+	"fmt"
+	"log/slog"
+	"net/url"
+	"sync"
+
+	"github.com/gin-gonic/gin"
+)
+
+// CrudService Handler[T] 调用的业务接口，Service[T] 是基于 Repository[T] 的默认实现。
+// Go 没有虚方法，嵌套 Service[T] 并重写方法后需要通过 NewHandler 的工厂传入才会生效：
+//
+//	type UserService struct{ *gowk.Service[User] }
+//
+//	func (s *UserService) Save(u *User) error { ... }
+//
+//	gowk.NewHandler(func(ctx *gin.Context) gowk.CrudService[User] {
+//		return &UserService{gowk.NewService[User](ctx)}
+//	})
+type CrudService[T any] interface {
+	Page(pageModel *PageModel[T], queryParam *T) (*PageModel[T], error)
+	One(queryParam *T) (T, error)
+	Update(postParam *T) error
+	Save(postParam *T) error
+	Delete(postParam *T) error
+}
+
+var _ CrudService[struct{}] = (*Service[struct{}])(nil)
+
+// CursorPager 支持游标分页的 Service，Service[T] 已实现；Handler 以游标分页方式处理列表请求时使用它，
+// 见 Handler.CursorService。
+type CursorPager[T any] interface {
+	CursorPage(page *CursorPage[T], queryParam *T) (*CursorPage[T], error)
+}
+
+var _ CursorPager[struct{}] = (*Service[struct{}])(nil)
+
+// Patcher 支持部分更新的 CrudService，Service[T] 已实现；Handler.Patch 优先使用它，没有实现时退回 Update。
+type Patcher[T any] interface {
+	Patch(postParam *T) error
+}
+
+var _ Patcher[struct{}] = (*Service[struct{}])(nil)
+
+// PageMode 列表的分页方式。
+type PageMode string
+
+const (
+	// PageOffset 按页码分页，响应 PageModel。
+	PageOffset PageMode = "offset"
+	// PageCursor 按游标分页，响应 CursorPage。
+	PageCursor PageMode = "cursor"
+)
+
+// CrudOp Handler[T] 的操作，传给 HandlerHooks.Authorize。
+type CrudOp string
+
+const (
+	OpPage   CrudOp = "page"
+	OpOne    CrudOp = "one"
+	OpSave   CrudOp = "save"
+	OpUpdate CrudOp = "update"
+	OpDelete CrudOp = "delete"
+)
+
+// HandlerHooks 包在 CrudService 调用前后的钩子，都是可选的；任一钩子返回 error 即中止并作为响应错误
+// （通过 ctx.Error，*ErrorCode 原样返回，如 ERR_FORBIDDEN）。
+// After* 在写入成功后执行，返回 error 时若请求开启了事务（TransactionHandler + Begin）会一并回滚。
+type HandlerHooks[T any] struct {
+	// Authorize 参数绑定之后、其他钩子之前执行，t 为请求参数（Page 时为查询条件）。
+	Authorize    func(ctx *gin.Context, op CrudOp, t *T) error
+	BeforeSave   func(ctx *gin.Context, t *T) error
+	AfterSave    func(ctx *gin.Context, t *T) error
+	BeforeUpdate func(ctx *gin.Context, t *T) error
+	AfterUpdate  func(ctx *gin.Context, t *T) error
+	BeforeDelete func(ctx *gin.Context, t *T) error
+	AfterDelete  func(ctx *gin.Context, t *T) error
+}
 
 type Handler[T any] struct {
+	// NewService 每个请求创建一次业务 Service，默认为 NewService[T]。
+	NewService func(ctx *gin.Context) CrudService[T]
+	Hooks      HandlerHooks[T]
+	// Pagination Page 的分页方式，为空时为 offset；Register 时为空则取配置 page.modes 中该资源的值。
+	Pagination PageMode
+	// CursorService 每个请求创建一次游标分页的 Service。为空时取 NewService 创建的 Service，
+	// 它没有实现 CursorPager 要到第一次请求时才能发现（记录日志并返回错误），自定义 NewService 时建议设置。
+	CursorService func(ctx *gin.Context) CursorPager[T]
+
+	cursorWarn sync.Once
+}
+
+// NewHandler 创建 Handler[T]，factory 为空时使用默认的 Service[T]。
+func NewHandler[T any](factory ...func(ctx *gin.Context) CrudService[T]) *Handler[T] {
+	h := &Handler[T]{}
+	if len(factory) > 0 {
+		h.NewService = factory[0]
+	}
+	return h
+}
+
+func (h *Handler[T]) service(ctx *gin.Context) CrudService[T] {
+	if h.NewService != nil {
+		return h.NewService(ctx)
+	}
+	return NewService[T](ctx)
+}
+
+// runHook 执行钩子（nil 时跳过），返回 error 时记入 ctx.Errors 并返回 false。
+func (h *Handler[T]) runHook(ctx *gin.Context, t *T, hook func(*gin.Context, *T) error) bool {
+	if hook == nil {
+		return true
+	}
+	if err := hook(ctx, t); err != nil {
+		ctx.Error(err)
+		return false
+	}
+	return true
 }
 
-func NewHandler[T any]() *Handler[T] {
-	return &Handler[T]{}
+// bindQuery 从请求参数绑定 Page / One 的查询条件，再清零未放开等值过滤的列，见 keepFilterable。
+func (h *Handler[T]) bindQuery(ctx *gin.Context, t *T) bool {
+	if err := ctx.ShouldBind(t); err != nil {
+		ctx.Error(err)
+		return false
+	}
+	keepFilterable(t)
+	return true
+}
+
+// bindID 路由带 :id 参数时，把它写入 T 的主键字段（db tag 标记 pk），覆盖请求体中的值。
+func (h *Handler[T]) bindID(ctx *gin.Context, t *T) bool {
+	id := ctx.Param("id")
+	if id == "" {
+		return true
+	}
+	if err := setPrimaryKey(t, id); err != nil {
+		ctx.Error(err)
+		return false
+	}
+	return true
+}
+
+// authorize 执行 Authorize 钩子，未设置时放行。
+func (h *Handler[T]) authorize(ctx *gin.Context, op CrudOp, t *T) bool {
+	if h.Hooks.Authorize == nil {
+		return true
+	}
+	if err := h.Hooks.Authorize(ctx, op, t); err != nil {
+		ctx.Error(err)
+		return false
+	}
+	return true
 }
 
+// Page 列表查询，调用时 Pagination 为 PageCursor 则返回 CursorPage。
 func (h *Handler[T]) Page() gin.HandlerFunc {
+	if h.Pagination == PageCursor {
+		return h.CursorPage()
+	}
 	return func(ctx *gin.Context) {
 		var page PageModel[T]
 		if err := ctx.ShouldBind(&page); err != nil {
@@ -17,12 +167,20 @@ func (h *Handler[T]) Page() gin.HandlerFunc {
 			return
 		}
 		var t T
-		if err := ctx.ShouldBind(&t); err != nil {
+		if !h.bindQuery(ctx, &t) {
+			return
+		}
+		spec, err := ParseQuerySpec[T](ctx.Request.URL.Query())
+		if err != nil {
 			ctx.Error(err)
 			return
 		}
-		service := NewService[T](ctx)
-		res, err := service.Page(&page, &t)
+		page.Query = spec
+		page.Normalize()
+		if !h.authorize(ctx, OpPage, &t) {
+			return
+		}
+		res, err := h.service(ctx).Page(&page, &t)
 		if err != nil {
 			ctx.Error(err)
 			return
@@ -31,6 +189,76 @@ func (h *Handler[T]) Page() gin.HandlerFunc {
 	}
 }
 
+// CursorPage 游标分页的列表查询：查询参数 size、cursor、total=true 以及与 Page 相同的条件、filter 与 sort；
+// 响应 CursorPage，Next / Prev 为替换了 cursor 参数的当前请求链接。Service 取自 CursorService，
+// 未设置时 NewService 创建的 Service 需要实现 CursorPager；注册后 RunContext 要求配置 PAGE_CURSOR_SECRET。
+func (h *Handler[T]) CursorPage() gin.HandlerFunc {
+	cursorPagination.Store(true)
+	return func(ctx *gin.Context) {
+		var page CursorPage[T]
+		if err := ctx.ShouldBind(&page); err != nil {
+			ctx.Error(err)
+			return
+		}
+		var t T
+		if !h.bindQuery(ctx, &t) {
+			return
+		}
+		spec, err := ParseQuerySpec[T](ctx.Request.URL.Query())
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		page.Query = spec
+		page.Normalize()
+		if !h.authorize(ctx, OpPage, &t) {
+			return
+		}
+		pager, ok := h.cursorService(ctx)
+		if !ok {
+			ctx.Error(errCursorUnsupported)
+			return
+		}
+		res, err := pager.CursorPage(&page, &t)
+		if err != nil {
+			ctx.Error(err)
+			return
+		}
+		res.Next = cursorLink(ctx.Request.URL, res.NextCursor)
+		res.Prev = cursorLink(ctx.Request.URL, res.PrevCursor)
+		Success(ctx, res)
+	}
+}
+
+// cursorLink 把请求链接的 cursor 参数替换为 cursor，cursor 为空时返回空。
+func cursorLink(u *url.URL, cursor string) string {
+	if cursor == "" {
+		return ""
+	}
+	query := u.Query()
+	query.Set("cursor", cursor)
+	return u.Path + "?" + query.Encode()
+}
+
+var errCursorUnsupported = NewError("不支持游标分页")
+
+// cursorService 返回游标分页的 Service：优先 CursorService，其次 NewService 创建的 Service（默认的 Service[T] 已实现）。
+// 后者没有实现 CursorPager 时返回 false，并在第一次发生时记录日志。
+func (h *Handler[T]) cursorService(ctx *gin.Context) (CursorPager[T], bool) {
+	if h.CursorService != nil {
+		return h.CursorService(ctx), true
+	}
+	svc := h.service(ctx)
+	pager, ok := svc.(CursorPager[T])
+	if !ok {
+		h.cursorWarn.Do(func() {
+			slog.ErrorContext(ctx, "Service 没有实现 CursorPager，不支持游标分页，可设置 Handler.CursorService",
+				"service", fmt.Sprintf("%T", svc), "path", ctx.FullPath())
+		})
+	}
+	return pager, ok
+}
+
 func (h *Handler[T]) Save() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var t T
@@ -38,27 +266,56 @@ func (h *Handler[T]) Save() gin.HandlerFunc {
 			ctx.Error(err)
 			return
 		}
-		service := NewService[T](ctx)
-		if err := service.Save(&t); err != nil {
+		if !h.authorize(ctx, OpSave, &t) || !h.runHook(ctx, &t, h.Hooks.BeforeSave) {
+			return
+		}
+		if err := h.service(ctx).Save(&t); err != nil {
 			ctx.Error(err)
 			return
 		}
+		if !h.runHook(ctx, &t, h.Hooks.AfterSave) {
+			return
+		}
 		Success(ctx, t)
 	}
 }
 
+// Update 按主键整体更新（PUT），Service[T] 会写入所有字段，包括零值。
 func (h *Handler[T]) Update() gin.HandlerFunc {
+	return h.update(func(svc CrudService[T], t *T) error { return svc.Update(t) })
+}
+
+// Patch 按主键部分更新（PATCH），Service 实现了 Patcher 时调用 Patch（Service[T] 只更新非零值的字段），
+// 否则调用 Update；钩子与 Update 相同。
+func (h *Handler[T]) Patch() gin.HandlerFunc {
+	return h.update(patch[T])
+}
+
+// patch 调用 Patcher.Patch，Service 没有实现时退回 Update。
+func patch[T any](svc CrudService[T], t *T) error {
+	if p, ok := svc.(Patcher[T]); ok {
+		return p.Patch(t)
+	}
+	return svc.Update(t)
+}
+
+func (h *Handler[T]) update(fn func(CrudService[T], *T) error) gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var t T
 		if err := ctx.ShouldBind(&t); err != nil {
 			ctx.Error(err)
 			return
 		}
-		service := NewService[T](ctx)
-		if err := service.Update(&t); err != nil {
+		if !h.bindID(ctx, &t) || !h.authorize(ctx, OpUpdate, &t) || !h.runHook(ctx, &t, h.Hooks.BeforeUpdate) {
+			return
+		}
+		if err := fn(h.service(ctx), &t); err != nil {
 			ctx.Error(err)
 			return
 		}
+		if !h.runHook(ctx, &t, h.Hooks.AfterUpdate) {
+			return
+		}
 		Success(ctx, t)
 	}
 }
@@ -66,12 +323,13 @@ func (h *Handler[T]) Update() gin.HandlerFunc {
 func (h *Handler[T]) One() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		var t T
-		if err := ctx.ShouldBind(&t); err != nil {
-			ctx.Error(err)
+		if !h.bindQuery(ctx, &t) {
+			return
+		}
+		if !h.bindID(ctx, &t) || !h.authorize(ctx, OpOne, &t) {
 			return
 		}
-		service := NewService[T](ctx)
-		res, err := service.One(&t)
+		res, err := h.service(ctx).One(&t)
 		if err != nil {
 			ctx.Error(err)
 			return
@@ -79,3 +337,27 @@ func (h *Handler[T]) One() gin.HandlerFunc {
 		Success(ctx, res)
 	}
 }
+
+// Delete 按主键删除，主键取路由参数 :id，没有时取请求参数。
+func (h *Handler[T]) Delete() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var t T
+		if ctx.Param("id") == "" {
+			if err := ctx.ShouldBind(&t); err != nil {
+				ctx.Error(err)
+				return
+			}
+		}
+		if !h.bindID(ctx, &t) || !h.authorize(ctx, OpDelete, &t) || !h.runHook(ctx, &t, h.Hooks.BeforeDelete) {
+			return
+		}
+		if err := h.service(ctx).Delete(&t); err != nil {
+			ctx.Error(err)
+			return
+		}
+		if !h.runHook(ctx, &t, h.Hooks.AfterDelete) {
+			return
+		}
+		Success(ctx, nil)
+	}
+}
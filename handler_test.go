@@ -0,0 +1,81 @@
+package gowk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testItem struct {
+	ID   int64  `json:"id" form:"id"`
+	Name string `json:"name" form:"name"`
+}
+
+// testItemService 嵌套默认 Service 并重写 Save，通过工厂注入 Handler。
+type testItemService struct {
+	*Service[testItem]
+	calls *[]string
+}
+
+func (s *testItemService) Save(t *testItem) error {
+	*s.calls = append(*s.calls, "save")
+	t.ID = 42
+	return nil
+}
+
+func TestHandlerServiceFactoryAndHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	var calls []string
+	h := NewHandler(func(ctx *gin.Context) CrudService[testItem] {
+		return &testItemService{Service: NewService[testItem](ctx), calls: &calls}
+	})
+	h.Hooks = HandlerHooks[testItem]{
+		Authorize: func(ctx *gin.Context, op CrudOp, item *testItem) error {
+			calls = append(calls, "authorize:"+string(op))
+			if item.Name == "root" {
+				return ERR_FORBIDDEN
+			}
+			return nil
+		},
+		BeforeSave: func(ctx *gin.Context, item *testItem) error {
+			calls = append(calls, "before")
+			item.Name = strings.TrimSpace(item.Name)
+			return nil
+		},
+		AfterSave: func(ctx *gin.Context, item *testItem) error {
+			calls = append(calls, "after")
+			if item.ID != 42 {
+				return errors.New("id not set")
+			}
+			return nil
+		},
+	}
+	engine := gin.New()
+	engine.Use(GlobalErrorHandler())
+	engine.POST("/items", h.Save())
+	post := func(body string) *http.Request {
+		req := httptest.NewRequest(http.MethodPost, "/items", strings.NewReader(body))
+		req.Header.Set("Content-Type", "application/json")
+		return req
+	}
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, post(`{"name":" a "}`))
+	if w.Code != http.StatusOK || !strings.Contains(w.Body.String(), `"id":42`) || !strings.Contains(w.Body.String(), `"name":"a"`) {
+		t.Fatalf("save: %d %s", w.Code, w.Body.String())
+	}
+	if strings.Join(calls, ",") != "authorize:save,before,save,after" {
+		t.Fatalf("calls = %v", calls)
+	}
+
+	calls = nil
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, post(`{"name":"root"}`))
+	if w.Code != http.StatusForbidden || strings.Join(calls, ",") != "authorize:save" {
+		t.Fatalf("forbidden: %d %v", w.Code, calls)
+	}
+}
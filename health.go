@@ -0,0 +1,273 @@
+package gowk
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// HealthState 外部依赖的健康状态。
+type HealthState int32
+
+const (
+	// HealthDisabled 未配置（或配置无法解析），不参与就绪判断。
+	HealthDisabled HealthState = iota
+	// HealthConnecting 已配置，后台首次连接尚未成功。
+	HealthConnecting
+	// HealthReady 已连接，且最近一次探活成功。
+	HealthReady
+	// HealthLost 曾经就绪，运行期探活失败。
+	HealthLost
+)
+
+func (s HealthState) String() string {
+	switch s {
+	case HealthDisabled:
+		return "disabled"
+	case HealthConnecting:
+		return "connecting"
+	case HealthReady:
+		return "ready"
+	case HealthLost:
+		return "lost"
+	}
+	return "unknown"
+}
+
+// MarshalText 让 HealthState 在 JSON 中输出为字符串。
+func (s HealthState) MarshalText() ([]byte, error) {
+	return []byte(s.String()), nil
+}
+
+// DependencyHealth 是某个依赖的健康快照，用于 /readyz 输出。
+type DependencyHealth struct {
+	Name      string      `json:"name"`
+	State     HealthState `json:"state"`
+	LastError string      `json:"lastError,omitempty"`
+	Attempts  int         `json:"attempts"`
+	Since     time.Time   `json:"since"`
+	// Optional 不可用时有回落（如只读副本回落到主库），不影响就绪判断。
+	Optional bool `json:"optional,omitempty"`
+}
+
+// healthTracker 记录单个依赖的状态，由后台重试与探活 goroutine 写入。
+type healthTracker struct {
+	mu       sync.RWMutex
+	name     string
+	state    HealthState
+	lastErr  error
+	attempts int
+	since    time.Time
+	optional bool
+}
+
+func newHealthTracker(name string) *healthTracker {
+	return &healthTracker{name: name, since: time.Now()}
+}
+
+var (
+	pgHealth    = newHealthTracker("postgres")
+	redisHealth = newHealthTracker("redis")
+)
+
+// set 切换状态；状态不变时只刷新 lastErr，不重置 since。
+func (h *healthTracker) set(state HealthState, err error) {
+	h.mu.Lock()
+	defer h.mu.Unlock()
+	if h.state != state {
+		h.state = state
+		h.since = time.Now()
+	}
+	h.lastErr = err
+}
+
+// observe 供 retryBackground 回调：记录第 n 次连接尝试的结果。
+func (h *healthTracker) observe(n int, err error) {
+	h.mu.Lock()
+	h.attempts = n
+	h.mu.Unlock()
+	if err != nil {
+		h.set(HealthConnecting, err)
+		return
+	}
+	h.set(HealthReady, nil)
+}
+
+func (h *healthTracker) snapshot() DependencyHealth {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+	d := DependencyHealth{
+		Name:     h.name,
+		State:    h.state,
+		Attempts: h.attempts,
+		Since:    h.since,
+		Optional: h.optional,
+	}
+	if h.lastErr != nil {
+		d.LastError = h.lastErr.Error()
+	}
+	return d
+}
+
+// HealthStatus 返回 Postgres（默认实例、已初始化的命名实例 "postgres:<name>" 与只读副本 "postgres-replica-<n>"）/ Redis 的健康快照。
+func HealthStatus() []DependencyHealth {
+	deps := []DependencyHealth{pgHealth.snapshot()}
+	for _, p := range namedPostgresInstances() {
+		deps = append(deps, p.health.snapshot())
+	}
+	for _, p := range postgresReplicas() {
+		deps = append(deps, p.health.snapshot())
+	}
+	return append(deps, redisHealth.snapshot())
+}
+
+// IsReady 判断所有已启用的依赖与 critical 自定义检查是否就绪；HealthDisabled 视为不参与判断。
+func IsReady() bool {
+	return CheckHealth(context.Background()).Ready
+}
+
+// monitorBackground 在依赖连上之后按 interval 周期探活，
+// 失败标记为 HealthLost，恢复后回到 HealthReady；ctx 取消即退出。
+// 连接本身的重连交给 pgxpool / go-redis，这里只负责把运行期故障反映到健康状态上。
+func monitorBackground(ctx context.Context, h *healthTracker, interval, timeout time.Duration, ping func(context.Context) error) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pingCtx, cancel := context.WithTimeout(ctx, timeout)
+		err := ping(pingCtx)
+		cancel()
+		if ctx.Err() != nil {
+			return
+		}
+		prev := h.snapshot().State
+		if err != nil {
+			if prev != HealthLost {
+				slog.Warn(h.name+" 探活失败，标记为 lost", "err", err)
+			}
+			h.set(HealthLost, err)
+			continue
+		}
+		if prev != HealthReady {
+			slog.Info(h.name + " 探活恢复")
+		}
+		h.set(HealthReady, nil)
+	}
+}
+
+// Healthz 存活探针：进程能响应即返回 200，不检查外部依赖。
+func Healthz() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, OK)
+		ctx.Abort()
+	}
+}
+
+// Readyz 就绪探针：所有已启用依赖与 critical 自定义检查就绪时返回 200，否则返回 503，
+// data 中带上每个依赖的状态、最近错误与重试次数，以及每个自定义检查的结果。
+func Readyz() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := CheckHealth(ctx.Request.Context())
+		if report.Ready {
+			ctx.JSON(http.StatusOK, Result(report))
+		} else {
+			ctx.JSON(http.StatusServiceUnavailable, &ErrorCode{
+				Status: http.StatusServiceUnavailable,
+				Code:   http.StatusServiceUnavailable,
+				Msg:    "依赖未就绪",
+				Data:   report,
+			})
+		}
+		ctx.Abort()
+	}
+}
+
+// dependencyReady 判断名为 name 的依赖是否就绪：postgres / postgres:<name> / redis 看后台状态，
+// 其余名字对应 RegisterHealthCheck 注册的检查。返回 error 表示不可能就绪（未配置或未注册），无需再等。
+func dependencyReady(ctx context.Context, name string) (bool, error) {
+	var h *healthTracker
+	switch name {
+	case "postgres":
+		h = pgHealth
+	case "redis":
+		h = redisHealth
+	default:
+		if db, ok := strings.CutPrefix(name, "postgres:"); ok {
+			p := postgresInstance(db)
+			if p == nil {
+				return false, fmt.Errorf("%s 未配置", name)
+			}
+			p.get()
+			h = p.health
+		}
+	}
+	if h != nil {
+		d := h.snapshot()
+		if d.State == HealthDisabled {
+			if d.LastError != "" {
+				return false, fmt.Errorf("%s 配置无效: %s", name, d.LastError)
+			}
+			return false, fmt.Errorf("%s 未配置", name)
+		}
+		return d.State == HealthReady, nil
+	}
+	for _, c := range registeredHealthChecks() {
+		if c.name == name {
+			return c.run(ctx).Healthy, nil
+		}
+	}
+	return false, fmt.Errorf("未知依赖 %s", name)
+}
+
+// waitForDependencies 阻塞直到 names 全部就绪；超时或 ctx 取消返回 error。
+func waitForDependencies(ctx context.Context, names []string, timeout time.Duration) error {
+	if len(names) == 0 {
+		return nil
+	}
+	if timeout <= 0 {
+		timeout = conf().Lifecycle.StartupWaitTimeout
+	}
+	slog.Info("等待依赖就绪", "deps", names, "timeout", timeout)
+	start := time.Now()
+	waitCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	ticker := time.NewTicker(200 * time.Millisecond)
+	defer ticker.Stop()
+	for {
+		var pending []string
+		for _, name := range names {
+			ready, err := dependencyReady(waitCtx, name)
+			if err != nil {
+				return fmt.Errorf("启动依赖 %s 不可用: %w", name, err)
+			}
+			if !ready {
+				pending = append(pending, name)
+			}
+		}
+		if len(pending) == 0 {
+			slog.Info("依赖已就绪", "deps", names, "elapsed", time.Since(start).Round(time.Millisecond))
+			return nil
+		}
+		select {
+		case <-waitCtx.Done():
+			if ctx.Err() != nil {
+				return ctx.Err()
+			}
+			return fmt.Errorf("等待依赖就绪超时 %s，未就绪: %v", timeout, pending)
+		case <-ticker.C:
+		}
+	}
+}
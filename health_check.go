@@ -0,0 +1,143 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// CheckResult 是一次自定义健康检查的结果。
+type CheckResult struct {
+	Name      string    `json:"name"`
+	Critical  bool      `json:"critical"`
+	Healthy   bool      `json:"healthy"`
+	Error     string    `json:"error,omitempty"`
+	Duration  string    `json:"duration"`
+	CheckedAt time.Time `json:"checkedAt"`
+}
+
+// HealthReport 汇总内置依赖与自定义检查，Ready 为最终就绪结论。
+type HealthReport struct {
+	Ready        bool               `json:"ready"`
+	Dependencies []DependencyHealth `json:"dependencies"`
+	Checks       []CheckResult      `json:"checks,omitempty"`
+}
+
+// healthCheck 是注册进来的单个检查，结果按 conf().Health.CacheTTL 缓存，
+// 同一检查的并发调用共用一次执行。
+type healthCheck struct {
+	name     string
+	critical bool
+	fn       func(context.Context) error
+
+	mu   sync.Mutex
+	last CheckResult
+}
+
+var (
+	healthChecksMu sync.RWMutex
+	healthChecks   []*healthCheck
+)
+
+// RegisterHealthCheck 注册自定义健康检查（下游 gRPC 服务、消息队列等）。
+// critical 为 true 时检查失败会让 /readyz 返回 503；否则只在报告中体现。
+// fn 应尊重 ctx 的超时；即便不尊重，超过 HEALTH_CHECK_TIMEOUT 也会按失败处理。
+// 同名重复注册会覆盖之前的检查。
+func RegisterHealthCheck(name string, critical bool, fn func(ctx context.Context) error) {
+	if name == "" || fn == nil {
+		return
+	}
+	c := &healthCheck{name: name, critical: critical, fn: fn}
+	healthChecksMu.Lock()
+	defer healthChecksMu.Unlock()
+	for i, old := range healthChecks {
+		if old.name == name {
+			healthChecks[i] = c
+			return
+		}
+	}
+	healthChecks = append(healthChecks, c)
+}
+
+func registeredHealthChecks() []*healthCheck {
+	healthChecksMu.RLock()
+	defer healthChecksMu.RUnlock()
+	return append([]*healthCheck(nil), healthChecks...)
+}
+
+// run 执行检查；缓存未过期时直接返回上次结果。
+func (c *healthCheck) run(ctx context.Context) CheckResult {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if !c.last.CheckedAt.IsZero() && time.Since(c.last.CheckedAt) < conf().Health.CacheTTL {
+		return c.last
+	}
+
+	start := time.Now()
+	checkCtx, cancel := context.WithTimeout(ctx, conf().Health.Timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("health check panic: %v", r)
+			}
+		}()
+		done <- c.fn(checkCtx)
+	}()
+	var err error
+	select {
+	case err = <-done:
+	case <-checkCtx.Done():
+		err = checkCtx.Err()
+		if errors.Is(err, context.DeadlineExceeded) {
+			err = fmt.Errorf("health check timeout after %s", conf().Health.Timeout)
+		}
+	}
+
+	res := CheckResult{
+		Name:      c.name,
+		Critical:  c.critical,
+		Healthy:   err == nil,
+		Duration:  time.Since(start).Round(time.Millisecond).String(),
+		CheckedAt: time.Now(),
+	}
+	if err != nil {
+		res.Error = err.Error()
+	}
+	// 调用方 ctx 被取消（请求断开）不代表依赖有问题，不写缓存。
+	if ctx.Err() == nil {
+		c.last = res
+	}
+	return res
+}
+
+// CheckHealth 并发执行所有自定义检查，并与 Postgres / Redis 的状态汇总成报告。
+func CheckHealth(ctx context.Context) *HealthReport {
+	report := &HealthReport{Ready: true, Dependencies: HealthStatus()}
+	for _, d := range report.Dependencies {
+		if !d.Optional && d.State != HealthDisabled && d.State != HealthReady {
+			report.Ready = false
+		}
+	}
+
+	checks := registeredHealthChecks()
+	report.Checks = make([]CheckResult, len(checks))
+	var wg sync.WaitGroup
+	for i, c := range checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			report.Checks[i] = c.run(ctx)
+		}()
+	}
+	wg.Wait()
+	for _, r := range report.Checks {
+		if r.Critical && !r.Healthy {
+			report.Ready = false
+		}
+	}
+	return report
+}
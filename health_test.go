@@ -0,0 +1,146 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestHealthTracker(t *testing.T) {
+	h := newHealthTracker("test")
+	if s := h.snapshot().State; s != HealthDisabled {
+		t.Fatalf("initial state = %v, want disabled", s)
+	}
+	h.observe(1, errors.New("refused"))
+	d := h.snapshot()
+	if d.State != HealthConnecting || d.Attempts != 1 || d.LastError != "refused" {
+		t.Fatalf("after failed attempt: %+v", d)
+	}
+	h.observe(2, nil)
+	d = h.snapshot()
+	if d.State != HealthReady || d.Attempts != 2 || d.LastError != "" {
+		t.Fatalf("after success: %+v", d)
+	}
+}
+
+func TestReadyz(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/readyz", Readyz())
+
+	prev := pgHealth.snapshot()
+	defer pgHealth.set(prev.State, nil)
+
+	pgHealth.set(HealthLost, errors.New("down"))
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusServiceUnavailable {
+		t.Fatalf("lost: status = %d, want 503", w.Code)
+	}
+
+	pgHealth.set(HealthReady, nil)
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code != http.StatusOK {
+		t.Fatalf("ready: status = %d, want 200, body=%s", w.Code, w.Body.String())
+	}
+}
+
+func TestCheckHealth(t *testing.T) {
+	healthChecksMu.Lock()
+	saved := healthChecks
+	healthChecks = nil
+	healthChecksMu.Unlock()
+	defer func() {
+		healthChecksMu.Lock()
+		healthChecks = saved
+		healthChecksMu.Unlock()
+	}()
+	prevTimeout := conf().Health.Timeout
+	conf().Health.Timeout = 50 * time.Millisecond
+	defer func() { conf().Health.Timeout = prevTimeout }()
+
+	calls := 0
+	RegisterHealthCheck("broker", false, func(ctx context.Context) error {
+		calls++
+		return errors.New("broker down")
+	})
+	RegisterHealthCheck("ok", true, func(ctx context.Context) error { return nil })
+
+	report := CheckHealth(context.Background())
+	if !report.Ready {
+		t.Fatalf("non-critical failure should not affect readiness: %+v", report)
+	}
+	if len(report.Checks) != 2 || report.Checks[0].Healthy || report.Checks[0].Error != "broker down" {
+		t.Fatalf("unexpected checks: %+v", report.Checks)
+	}
+	CheckHealth(context.Background())
+	if calls != 1 {
+		t.Fatalf("cached result expected, fn called %d times", calls)
+	}
+
+	RegisterHealthCheck("slow", true, func(ctx context.Context) error {
+		time.Sleep(time.Second)
+		return nil
+	})
+	start := time.Now()
+	report = CheckHealth(context.Background())
+	if report.Ready {
+		t.Fatal("critical timeout should fail readiness")
+	}
+	if time.Since(start) > 500*time.Millisecond {
+		t.Fatalf("timeout not enforced, took %s", time.Since(start))
+	}
+}
+
+func TestWaitForDependencies(t *testing.T) {
+	prev := pgHealth.snapshot()
+	defer pgHealth.set(prev.State, nil)
+
+	pgHealth.set(HealthDisabled, nil)
+	if err := waitForDependencies(context.Background(), []string{"postgres"}, time.Second); err == nil {
+		t.Fatal("disabled postgres should fail immediately")
+	}
+
+	pgHealth.set(HealthConnecting, nil)
+	go func() {
+		time.Sleep(100 * time.Millisecond)
+		pgHealth.set(HealthReady, nil)
+	}()
+	if err := waitForDependencies(context.Background(), []string{"postgres"}, 2*time.Second); err != nil {
+		t.Fatalf("expected postgres to become ready: %v", err)
+	}
+
+	pgHealth.set(HealthConnecting, nil)
+	if err := waitForDependencies(context.Background(), []string{"postgres"}, 100*time.Millisecond); err == nil {
+		t.Fatal("expected timeout while postgres is connecting")
+	}
+}
+
+func TestProbesDoNotConflictWithAppRoutes(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := New()
+	// 业务自己的 /healthz 不应因重复注册 panic，并且优先于 gowk 的探针。
+	engine.GET("/healthz", func(ctx *gin.Context) { ctx.String(http.StatusOK, "app") })
+
+	w := httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/healthz", nil))
+	if w.Code != http.StatusOK || w.Body.String() != "app" {
+		t.Fatalf("healthz = %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/readyz", nil))
+	if w.Code == http.StatusNotFound {
+		t.Fatalf("readyz should still be served: %d %s", w.Code, w.Body.String())
+	}
+	w = httptest.NewRecorder()
+	engine.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing", nil))
+	if w.Code != http.StatusNotFound {
+		t.Fatalf("missing = %d", w.Code)
+	}
+}
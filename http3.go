@@ -0,0 +1,66 @@
+package gowk
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+
+	"github.com/quic-go/quic-go/http3"
+)
+
+// startHTTP3 在 HTTP3_SERVER_ADDR 上以 HTTP/3（QUIC）服务同一个 engine，并为 TCP 响应加上 Alt-Svc。
+// HTTP/3 强制 TLS，未配置证书时返回 error；UDP 绑定失败同样返回 error，由调用方 fail-fast。
+func (h *HttpServer) startHTTP3(tlsConfig *tls.Config) error {
+	if !HasHTTP3() {
+		return nil
+	}
+	if tlsConfig == nil {
+		return errors.New("HTTP/3 需要 TLS 证书，请配置 TLS_CERT_FILE / TLS_KEY_FILE")
+	}
+	conn, err := listenPacket("udp", conf().Server.HTTP3Addr)
+	if err != nil {
+		return fmt.Errorf("HTTP/3 监听失败 addr=%s: %w", conf().Server.HTTP3Addr, err)
+	}
+	h3 := &http3.Server{
+		Handler:   h.Handler.Handler,
+		TLSConfig: tlsConfig,
+	}
+	h.http3 = h3
+	h.http3Conn = conn
+	h.Handler.Handler = altSvcHandler(h3, h.Handler.Handler)
+
+	slog.Info("HTTP/3 server running", "addr", conn.LocalAddr().String())
+	go func() {
+		if err := h3.Serve(conn); err != nil && !errors.Is(err, http.ErrServerClosed) && !errors.Is(err, net.ErrClosed) {
+			slog.Error("HTTP/3 server serve failed", "addr", conn.LocalAddr().String(), "err", err)
+			h.serveErr <- fmt.Errorf("HTTP/3 serve 失败 addr=%s: %w", conn.LocalAddr().String(), err)
+		}
+	}()
+	return nil
+}
+
+// stopHTTP3 先发 GOAWAY 等待请求结束，ctx 超时后强制关闭；Serve 不负责关闭 UDP 连接，这里一并释放。
+func (h *HttpServer) stopHTTP3(ctx context.Context) {
+	if h.http3 == nil {
+		return
+	}
+	if err := h.http3.Shutdown(ctx); err != nil && !errors.Is(err, context.DeadlineExceeded) {
+		slog.Error("HTTP/3 server shutdown failed", "err", err)
+	}
+	_ = h.http3Conn.Close()
+	slog.Info("HTTP/3 server stopped")
+}
+
+// altSvcHandler 在 TCP 响应上通告 HTTP/3 端点，浏览器据此升级到 QUIC。
+func altSvcHandler(h3 *http3.Server, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.ProtoMajor < 3 {
+			_ = h3.SetQUICHeaders(w.Header())
+		}
+		next.ServeHTTP(w, r)
+	})
+}
@@ -0,0 +1,81 @@
+package gowk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go/http3"
+)
+
+func TestHTTP3(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	dir := t.TempDir()
+	ca := newTestCert(t, "test-ca", nil, 1)
+	certFile, keyFile := newTestCert(t, "server", ca, 2).write(t, dir, "server")
+
+	udp, err := net.ListenPacket("udp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	h3Addr := udp.LocalAddr().String()
+	udp.Close()
+	prev := conf().Server.HTTP3Addr
+	SetHTTP3ServerAddr(h3Addr)
+	defer SetHTTP3ServerAddr(prev)
+
+	engine := gin.New()
+	engine.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, c.Request.Proto) })
+	addr := freeAddr(t)
+	h := &HttpServer{
+		Engine:  engine,
+		Handler: &http.Server{Addr: addr, Handler: engine},
+		TLS:     &TLSOptions{CertFile: certFile, KeyFile: keyFile},
+	}
+	if err := h.ServerRun(); err != nil {
+		t.Fatal(err)
+	}
+	defer h.ServerStop()
+
+	roots := x509.NewCertPool()
+	roots.AddCert(ca.cert)
+	tlsConf := &tls.Config{RootCAs: roots}
+
+	resp, err := (&http.Client{Transport: &http.Transport{TLSClientConfig: tlsConf}}).Get("https://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	if alt := resp.Header.Get("Alt-Svc"); !strings.Contains(alt, "h3=") {
+		t.Fatalf("Alt-Svc not advertised: %q", alt)
+	}
+
+	tr := &http3.Transport{TLSClientConfig: tlsConf}
+	defer tr.Close()
+	resp, err = (&http.Client{Transport: tr}).Get("https://" + h3Addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "HTTP/3.0" {
+		t.Fatalf("proto = %q, want HTTP/3.0", body)
+	}
+}
+
+func TestHTTP3RequiresTLS(t *testing.T) {
+	prev := conf().Server.HTTP3Addr
+	SetHTTP3ServerAddr("127.0.0.1:0")
+	defer SetHTTP3ServerAddr(prev)
+
+	h := &HttpServer{Engine: gin.New(), Handler: &http.Server{Addr: "127.0.0.1:0"}}
+	if err := h.ServerRun(); err == nil {
+		h.ServerStop()
+		t.Fatal("HTTP/3 without TLS should fail")
+	}
+}
@@ -2,6 +2,7 @@ package gowk
 
 import (
 	"context"
+	"crypto/tls"
 	"errors"
 	"fmt"
 	"log/slog"
@@ -10,6 +11,7 @@ import (
 	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/quic-go/quic-go/http3"
 )
 
 type RouteInfo struct {
@@ -22,41 +24,108 @@ type RouteInfo struct {
 type HttpServer struct {
 	Handler *http.Server
 	Engine  *gin.Engine
+	// TLS 为 nil 时使用 SetTLS / TLS_* 环境变量的配置；都未配置则走明文 HTTP。
+	TLS *TLSOptions
+
+	serveErr  chan error
+	http3     *http3.Server
+	http3Conn net.PacketConn
 }
 
 func New() *gin.Engine {
-	slog.SetDefault(Logger(slog.LevelInfo))
+	slog.SetDefault(Logger(logLevel))
 	engine := gin.New()
 	engine.Use(GlobalErrorHandler(), LogTrace(), Recover(), TransactionHandler())
-	engine.NoRoute(NotFound())
+	engine.NoRoute(probes(), NotFound())
 	engine.NoMethod(NotFound())
 	return engine
 }
 
-// ServerRun 同步执行 net.Listen 绑定端口，成功后把 Serve 放到 goroutine 里运行。
+// probes 在 NoRoute 中兜底响应 GET /healthz 与 /readyz：业务自己注册了同名路由时以业务的为准，
+// 不会因为重复注册而 panic。
+func probes() gin.HandlerFunc {
+	healthz, readyz := Healthz(), Readyz()
+	return func(ctx *gin.Context) {
+		if ctx.Request.Method != http.MethodGet {
+			return
+		}
+		switch ctx.Request.URL.Path {
+		case "/healthz":
+			healthz(ctx)
+		case "/readyz":
+			readyz(ctx)
+		default:
+			return
+		}
+		ctx.Abort()
+	}
+}
+
+// ServerRun 同步绑定端口（零停机重启时复用继承的监听），成功后把 Serve 放到 goroutine 里运行。
 // 监听失败（端口占用/地址非法等）直接返回 error，由调用方决定 fail-fast；
-// Serve 阶段的非 ErrServerClosed 错误只打日志，不再向上传递（此时端口已绑好，进程也已通告 running）。
+// Serve 阶段的非 ErrServerClosed 错误打日志并投递到 serveErrors()，由 RunContext 据此退出。
 func (h *HttpServer) ServerRun() error {
+	tlsConfig, err := h.prepare()
+	if err != nil {
+		return err
+	}
+	ln, err := listen(h.Handler.Addr)
+	if err != nil {
+		return fmt.Errorf("HTTP 监听失败 addr=%s: %w", h.Handler.Addr, err)
+	}
+	if err := h.startHTTP3(tlsConfig); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	h.serve(ln, tlsConfig != nil)
+	return nil
+}
+
+// prepare 补齐 Engine / Handler 并加载证书，返回 nil 表示明文 HTTP。
+// 证书在监听之前加载，配置错误不会占住端口。
+func (h *HttpServer) prepare() (*tls.Config, error) {
+	// TCP 与 HTTP/3 的 Serve 都可能出错，各留一个位置，避免 goroutine 阻塞。
+	h.serveErr = make(chan error, 2)
 	if h.Engine == nil {
 		h.Engine = gin.Default()
 	}
 	if h.Handler == nil {
 		h.Handler = &http.Server{
-			Addr:    httpServerAddr,
+			Addr:    conf().Server.HTTPAddr,
 			Handler: h.Engine,
 		}
 	}
-	ln, err := net.Listen("tcp", h.Handler.Addr)
+	tlsOpts := h.TLS
+	if tlsOpts == nil {
+		tlsOpts = tlsOptions()
+	}
+	if !tlsOpts.enabled() {
+		return nil, nil
+	}
+	r, err := newCertReloader(tlsOpts)
 	if err != nil {
-		return fmt.Errorf("HTTP 监听失败 addr=%s: %w", h.Handler.Addr, err)
+		return nil, fmt.Errorf("HTTP TLS 配置失败: %w", err)
 	}
-	slog.Info("HTTP server running", "addr", ln.Addr().String())
+	return r.config("h2", "http/1.1"), nil
+}
+
+// serve 在后台对已绑定的 ln 执行 Serve。
+func (h *HttpServer) serve(ln net.Listener, tlsEnabled bool) {
+	slog.Info("HTTP server running", "addr", ln.Addr().String(), "tls", tlsEnabled)
 	go func() {
 		if err := h.Handler.Serve(ln); err != nil && !errors.Is(err, http.ErrServerClosed) {
 			slog.Error("HTTP server serve failed", "addr", ln.Addr().String(), "err", err)
+			h.serveErr <- fmt.Errorf("HTTP serve 失败 addr=%s: %w", ln.Addr().String(), err)
 		}
 	}()
-	return nil
+}
+
+// serveErrors 返回 Serve 阶段的异常；未启动时为 nil channel（select 时永远阻塞）。
+func (h *HttpServer) serveErrors() <-chan error {
+	return h.serveErr
 }
 
 func (h *HttpServer) ServerStop() {
@@ -67,5 +136,6 @@ func (h *HttpServer) ServerStop() {
 		// 不走 Fatal，避免跳过 defer 导致资源无法释放
 		slog.Error("HTTP server shutdown failed", "err", err)
 	}
+	h.stopHTTP3(ctx)
 	slog.Info("HTTP server stopped")
 }
@@ -0,0 +1,141 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sort"
+	"sync"
+	"time"
+)
+
+// HookPhase 生命周期钩子的执行阶段。
+type HookPhase int
+
+const (
+	// PreStart 依赖初始化已触发、监听尚未建立；返回 error 中止启动。
+	PreStart HookPhase = iota
+	// PostStart HTTP / gRPC 均已监听；返回 error 中止启动并走完整关闭流程。
+	PostStart
+	// PreShutdown 收到退出信号、Server 仍在服务；错误只记日志。
+	PreShutdown
+	// PostShutdown Server 已停止、closePostgres / closeRedis 之前；适合刷写缓冲。错误只记日志。
+	PostShutdown
+)
+
+func (p HookPhase) String() string {
+	switch p {
+	case PreStart:
+		return "pre-start"
+	case PostStart:
+		return "post-start"
+	case PreShutdown:
+		return "pre-shutdown"
+	case PostShutdown:
+		return "post-shutdown"
+	}
+	return "unknown"
+}
+
+// Hook 生命周期钩子。同一阶段按 Order 升序执行，Order 相同按注册顺序；
+// Timeout <= 0 时使用 HOOK_TIMEOUT（默认 10s）。
+type Hook struct {
+	Name    string
+	Phase   HookPhase
+	Order   int
+	Timeout time.Duration
+	Fn      func(ctx context.Context) error
+}
+
+var (
+	hooksMu sync.Mutex
+	hooks   []Hook
+)
+
+// RegisterHook 注册全局生命周期钩子，对之后的每次 Run / RunContext 生效。
+// 也可以通过 ServerConfig.Hooks 只对单次运行注册。
+func RegisterHook(h Hook) {
+	if h.Fn == nil {
+		return
+	}
+	hooksMu.Lock()
+	defer hooksMu.Unlock()
+	hooks = append(hooks, h)
+}
+
+// OnStart 注册 PostStart 钩子：监听建立之后执行，例如启动消息消费者。
+func OnStart(name string, fn func(ctx context.Context) error) {
+	RegisterHook(Hook{Name: name, Phase: PostStart, Fn: fn})
+}
+
+// OnShutdown 注册 PostShutdown 钩子：Server 停止之后、关闭 Postgres / Redis 之前执行。
+func OnShutdown(name string, fn func(ctx context.Context) error) {
+	RegisterHook(Hook{Name: name, Phase: PostShutdown, Fn: fn})
+}
+
+// phaseHooks 合并全局与 ServerConfig 上的钩子，筛出指定阶段并排序。
+func phaseHooks(config *ServerConfig, phase HookPhase) []Hook {
+	hooksMu.Lock()
+	all := append([]Hook(nil), hooks...)
+	hooksMu.Unlock()
+	if config != nil {
+		all = append(all, config.Hooks...)
+	}
+	var res []Hook
+	for _, h := range all {
+		if h.Phase == phase && h.Fn != nil {
+			res = append(res, h)
+		}
+	}
+	sort.SliceStable(res, func(i, j int) bool { return res[i].Order < res[j].Order })
+	return res
+}
+
+// runHooks 依次执行某阶段的钩子。
+// abortOnError 为 true（启动阶段）时遇到第一个错误即返回；否则记日志后继续执行后续钩子。
+func runHooks(ctx context.Context, config *ServerConfig, phase HookPhase, abortOnError bool) error {
+	for _, h := range phaseHooks(config, phase) {
+		start := time.Now()
+		err := runHook(ctx, h)
+		if err == nil {
+			slog.Info("生命周期钩子执行完成", "phase", phase.String(), "hook", h.Name,
+				"elapsed", time.Since(start).Round(time.Millisecond))
+			continue
+		}
+		slog.Error("生命周期钩子执行失败", "phase", phase.String(), "hook", h.Name,
+			"elapsed", time.Since(start).Round(time.Millisecond), "err", err)
+		if abortOnError {
+			return fmt.Errorf("%s 钩子 %s 失败: %w", phase, h.Name, err)
+		}
+	}
+	return nil
+}
+
+// runHook 带超时执行单个钩子；钩子不响应 ctx 时超时后直接返回错误，不再等待。
+func runHook(ctx context.Context, h Hook) error {
+	timeout := h.Timeout
+	if timeout <= 0 {
+		timeout = conf().Lifecycle.HookTimeout
+	}
+	hookCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+	done := make(chan error, 1)
+	go func() {
+		defer func() {
+			if r := recover(); r != nil {
+				done <- fmt.Errorf("hook panic: %v", r)
+			}
+		}()
+		done <- h.Fn(hookCtx)
+	}()
+	select {
+	case err := <-done:
+		return err
+	case <-hookCtx.Done():
+		if errors.Is(hookCtx.Err(), context.DeadlineExceeded) {
+			return fmt.Errorf("hook timeout after %s", timeout)
+		}
+		return hookCtx.Err()
+	}
+}
@@ -12,7 +12,22 @@ import (
 	"github.com/jackc/pgx/v5/tracelog"
 )
 
-func Logger(l slog.Level) *slog.Logger {
+// logLevel 是 New() 默认 logger 的级别，初始取 Config.LogLevel（LOG_LEVEL，默认 info），
+// 运行期可通过 SetLogLevel 或管理端口的 PUT /loglevel 调整。
+var logLevel = func() *slog.LevelVar {
+	v := new(slog.LevelVar)
+	v.Set(conf().LogLevel)
+	return v
+}()
+
+// SetLogLevel 运行期调整默认 logger 的级别。
+func SetLogLevel(l slog.Level) { logLevel.Set(l) }
+
+// LogLevel 返回默认 logger 当前的级别。
+func LogLevel() slog.Level { return logLevel.Level() }
+
+// Logger 创建带 trace 信息的 logger。传入 *slog.LevelVar 时级别可在运行期调整。
+func Logger(l slog.Leveler) *slog.Logger {
 	options := &slog.HandlerOptions{
 		AddSource: true,
 		Level:     l,
@@ -0,0 +1,434 @@
+package gowk
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"hash/fnv"
+	"io/fs"
+	"log/slog"
+	"path"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// 迁移文件命名为 <version>_<name>.up.sql / <version>_<name>.down.sql（.up 可省略），version 为正整数，
+// 如 0001_init.up.sql、20240501120000_add_index.down.sql；按 version 升序执行，其他扩展名的文件忽略。
+// 每个迁移在一个事务中执行并写入版本表；文件首行为 "-- gowk:no-transaction" 时不包事务
+// （如 CREATE INDEX CONCURRENTLY，此时文件中只能有一条语句），失败后需要人工处理。
+var migrationFilePattern = regexp.MustCompile(`^(\d+)_([^.]+)(?:\.(up|down))?\.sql$`)
+
+const migrationNoTx = "-- gowk:no-transaction"
+
+// Migrator 基于 embed.FS 的 SQL 迁移。多个副本同时启动时由 Postgres advisory lock 保证只有一个在执行，
+// 其余等锁释放后发现没有待执行的迁移直接返回。
+//
+//	//go:embed migrations/*.sql
+//	var migrations embed.FS
+//
+//	gowk.RegisterMigrator(gowk.NewMigrator(migrations, "migrations"))
+type Migrator struct {
+	FS  fs.FS
+	Dir string
+	// Table 记录已执行版本的表，可带 schema（如 app.schema_migrations），默认 schema_migrations。
+	Table string
+	// DB 命名实例名，为空时使用默认实例（主库）。
+	DB string
+	// DryRun 为 true 时 Up / Down 只返回将要执行的迁移，不修改数据库。
+	DryRun bool
+	// SkipOnStart 为 true 时 RegisterMigrator 不在启动时执行 Up，只提供状态查询，迁移由业务按需调用。
+	SkipOnStart bool
+	// Timeout 启动时执行 Up 的超时，<= 0 时为 5m；不受 HOOK_TIMEOUT 限制。
+	Timeout time.Duration
+}
+
+// MigrationStatus 单个迁移的状态。
+type MigrationStatus struct {
+	Version   int64      `json:"version"`
+	Name      string     `json:"name"`
+	Applied   bool       `json:"applied"`
+	AppliedAt *time.Time `json:"appliedAt,omitempty"`
+	HasDown   bool       `json:"hasDown"`
+	// Missing 版本表中已执行、但迁移文件里已经没有的版本。
+	Missing bool `json:"missing,omitempty"`
+}
+
+type migration struct {
+	version  int64
+	name     string
+	up, down string
+	hasDown  bool
+}
+
+func NewMigrator(fsys fs.FS, dir string) *Migrator {
+	return &Migrator{FS: fsys, Dir: dir}
+}
+
+func (m *Migrator) table() string {
+	if m.Table == "" {
+		return "schema_migrations"
+	}
+	return m.Table
+}
+
+// tableIdent 转义后的版本表名。
+func (m *Migrator) tableIdent() string {
+	return pgx.Identifier(strings.Split(m.table(), ".")).Sanitize()
+}
+
+// lockKey advisory lock 的 key，同一个库上不同版本表的迁移互不阻塞。
+func (m *Migrator) lockKey() int64 {
+	h := fnv.New64a()
+	h.Write([]byte("gowk:migrate:" + m.table()))
+	return int64(h.Sum64())
+}
+
+// load 读取并校验迁移文件，按 version 升序返回。
+func (m *Migrator) load() ([]migration, error) {
+	dir := m.Dir
+	if dir == "" {
+		dir = "."
+	}
+	entries, err := fs.ReadDir(m.FS, dir)
+	if err != nil {
+		return nil, fmt.Errorf("读取迁移目录 %s 失败: %w", dir, err)
+	}
+	byVersion := map[int64]*migration{}
+	for _, e := range entries {
+		if e.IsDir() || !strings.HasSuffix(e.Name(), ".sql") {
+			continue
+		}
+		match := migrationFilePattern.FindStringSubmatch(e.Name())
+		if match == nil {
+			return nil, fmt.Errorf("迁移文件名 %s 不合法，应为 <version>_<name>.up.sql / .down.sql", e.Name())
+		}
+		version, err := strconv.ParseInt(match[1], 10, 64)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("迁移文件 %s 的版本号无效", e.Name())
+		}
+		body, err := fs.ReadFile(m.FS, path.Join(dir, e.Name()))
+		if err != nil {
+			return nil, fmt.Errorf("读取迁移文件 %s 失败: %w", e.Name(), err)
+		}
+		mg := byVersion[version]
+		if mg == nil {
+			mg = &migration{version: version, name: match[2]}
+			byVersion[version] = mg
+		} else if mg.name != match[2] {
+			return nil, fmt.Errorf("迁移版本 %d 重复: %s 与 %s", version, mg.name, match[2])
+		}
+		if match[3] == "down" {
+			if mg.hasDown {
+				return nil, fmt.Errorf("迁移版本 %d 的 down 文件重复", version)
+			}
+			mg.down, mg.hasDown = string(body), true
+		} else {
+			if mg.up != "" {
+				return nil, fmt.Errorf("迁移版本 %d 的 up 文件重复", version)
+			}
+			mg.up = string(body)
+		}
+	}
+	res := make([]migration, 0, len(byVersion))
+	for _, mg := range byVersion {
+		if mg.up == "" {
+			return nil, fmt.Errorf("迁移版本 %d 缺少 up 文件", mg.version)
+		}
+		res = append(res, *mg)
+	}
+	slices.SortFunc(res, func(a, b migration) int { return cmp.Compare(a.version, b.version) })
+	return res, nil
+}
+
+func (m *Migrator) pool() (*pgxpool.Pool, error) {
+	p := postgresInstance(m.DB)
+	if p == nil {
+		return nil, fmt.Errorf("postgres %s 未配置", m.DB)
+	}
+	pool := p.get()
+	if pool == nil {
+		return nil, errors.New("postgres unavailable")
+	}
+	return pool, nil
+}
+
+type appliedMigration struct {
+	name      string
+	appliedAt time.Time
+}
+
+// applied 读取版本表，表不存在时返回空。
+func (m *Migrator) applied(ctx context.Context, q interface {
+	Query(context.Context, string, ...any) (pgx.Rows, error)
+	QueryRow(context.Context, string, ...any) pgx.Row
+}) (map[int64]appliedMigration, error) {
+	var exists bool
+	if err := q.QueryRow(ctx, "SELECT to_regclass($1) IS NOT NULL", m.tableIdent()).Scan(&exists); err != nil {
+		return nil, fmt.Errorf("检查版本表失败: %w", err)
+	}
+	res := map[int64]appliedMigration{}
+	if !exists {
+		return res, nil
+	}
+	rows, err := q.Query(ctx, "SELECT version, name, applied_at FROM "+m.tableIdent())
+	if err != nil {
+		return nil, fmt.Errorf("读取版本表失败: %w", err)
+	}
+	defer rows.Close()
+	for rows.Next() {
+		var v int64
+		var a appliedMigration
+		if err := rows.Scan(&v, &a.name, &a.appliedAt); err != nil {
+			return nil, err
+		}
+		res[v] = a
+	}
+	return res, rows.Err()
+}
+
+// Status 返回所有迁移（含版本表中有、文件里已没有的）的执行状态，按 version 升序。
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := m.pool()
+	if err != nil {
+		return nil, err
+	}
+	applied, err := m.applied(ctx, pool)
+	if err != nil {
+		return nil, err
+	}
+	return migrationStatus(migrations, applied), nil
+}
+
+func migrationStatus(migrations []migration, applied map[int64]appliedMigration) []MigrationStatus {
+	var res []MigrationStatus
+	seen := map[int64]bool{}
+	for _, mg := range migrations {
+		s := MigrationStatus{Version: mg.version, Name: mg.name, HasDown: mg.hasDown}
+		if a, ok := applied[mg.version]; ok {
+			s.Applied, s.AppliedAt = true, &a.appliedAt
+		}
+		seen[mg.version] = true
+		res = append(res, s)
+	}
+	for v, a := range applied {
+		if !seen[v] {
+			res = append(res, MigrationStatus{Version: v, Name: a.name, Applied: true, AppliedAt: &a.appliedAt, Missing: true})
+		}
+	}
+	slices.SortFunc(res, func(a, b MigrationStatus) int { return cmp.Compare(a.Version, b.Version) })
+	return res
+}
+
+// Up 按 version 升序执行所有未执行的迁移，返回本次执行（DryRun 时为将要执行）的迁移。
+// 某个迁移失败时停止，之前已成功的保持生效。
+func (m *Migrator) Up(ctx context.Context) ([]MigrationStatus, error) {
+	return m.run(ctx, func(migrations []migration, applied map[int64]appliedMigration) ([]migration, error) {
+		return planUp(migrations, applied), nil
+	}, true)
+}
+
+// Down 按 version 降序回滚最近执行的 steps 个迁移，steps <= 0 时回滚一个；缺少 down 文件时不执行任何回滚。
+func (m *Migrator) Down(ctx context.Context, steps int) ([]MigrationStatus, error) {
+	return m.run(ctx, func(migrations []migration, applied map[int64]appliedMigration) ([]migration, error) {
+		return planDown(migrations, applied, steps)
+	}, false)
+}
+
+// planUp 按 version 升序选出未执行的迁移（migrations 已按 version 排序）。
+func planUp(migrations []migration, applied map[int64]appliedMigration) []migration {
+	var pending []migration
+	var latest int64
+	for v := range applied {
+		latest = max(latest, v)
+	}
+	for _, mg := range migrations {
+		if _, ok := applied[mg.version]; ok {
+			continue
+		}
+		if mg.version < latest {
+			slog.Warn("迁移版本早于已执行的最新版本，仍将执行", "version", mg.version, "name", mg.name, "latest", latest)
+		}
+		pending = append(pending, mg)
+	}
+	return pending
+}
+
+// planDown 按 version 降序选出最近执行的 steps 个迁移，其中任一个没有 down 文件时返回 error。
+func planDown(migrations []migration, applied map[int64]appliedMigration, steps int) ([]migration, error) {
+	steps = max(steps, 1)
+	var targets []migration
+	for _, mg := range slices.Backward(migrations) {
+		if len(targets) == steps {
+			break
+		}
+		if _, ok := applied[mg.version]; !ok {
+			continue
+		}
+		if !mg.hasDown {
+			return nil, fmt.Errorf("迁移版本 %d_%s 没有 down 文件，无法回滚", mg.version, mg.name)
+		}
+		targets = append(targets, mg)
+	}
+	return targets, nil
+}
+
+// run 持有 advisory lock 执行 plan 选出的迁移；up 为 false 时执行 down 并删除版本记录。
+func (m *Migrator) run(ctx context.Context, plan func([]migration, map[int64]appliedMigration) ([]migration, error), up bool) ([]MigrationStatus, error) {
+	migrations, err := m.load()
+	if err != nil {
+		return nil, err
+	}
+	pool, err := m.pool()
+	if err != nil {
+		return nil, err
+	}
+	conn, err := pool.Acquire(ctx)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Release()
+
+	if !m.DryRun {
+		// advisory lock 是会话级的，加锁、迁移与解锁都在同一个连接上。
+		if _, err := conn.Exec(ctx, "SELECT pg_advisory_lock($1)", m.lockKey()); err != nil {
+			return nil, fmt.Errorf("获取迁移锁失败: %w", err)
+		}
+		defer func() {
+			if _, err := conn.Exec(context.WithoutCancel(ctx), "SELECT pg_advisory_unlock($1)", m.lockKey()); err != nil {
+				slog.Error("释放迁移锁失败", "err", err)
+			}
+		}()
+		_, err := conn.Exec(ctx, "CREATE TABLE IF NOT EXISTS "+m.tableIdent()+
+			" (version bigint PRIMARY KEY, name text NOT NULL, applied_at timestamptz NOT NULL DEFAULT now())")
+		if err != nil {
+			return nil, fmt.Errorf("创建版本表失败: %w", err)
+		}
+	}
+	applied, err := m.applied(ctx, conn)
+	if err != nil {
+		return nil, err
+	}
+	targets, err := plan(migrations, applied)
+	if err != nil {
+		return nil, err
+	}
+	direction := "up"
+	if !up {
+		direction = "down"
+	}
+	var done []MigrationStatus
+	for _, mg := range targets {
+		s := MigrationStatus{Version: mg.version, Name: mg.name, HasDown: mg.hasDown, Applied: !up}
+		if m.DryRun {
+			slog.Info("迁移 dry-run", "direction", direction, "version", mg.version, "name", mg.name)
+			done = append(done, s)
+			continue
+		}
+		start := time.Now()
+		if err := m.apply(ctx, conn, mg, up); err != nil {
+			return done, fmt.Errorf("迁移 %d_%s %s 失败: %w", mg.version, mg.name, direction, err)
+		}
+		slog.Info("迁移已执行", "direction", direction, "version", mg.version, "name", mg.name,
+			"elapsed", time.Since(start).Round(time.Millisecond))
+		s.Applied = up
+		done = append(done, s)
+	}
+	return done, nil
+}
+
+// apply 执行单个迁移并更新版本表，默认在一个事务中完成。
+func (m *Migrator) apply(ctx context.Context, conn *pgxpool.Conn, mg migration, up bool) error {
+	sql, record, args := mg.up, "INSERT INTO "+m.tableIdent()+" (version, name) VALUES ($1, $2)", []any{mg.version, mg.name}
+	if !up {
+		sql, record, args = mg.down, "DELETE FROM "+m.tableIdent()+" WHERE version = $1", []any{mg.version}
+	}
+	if strings.HasPrefix(strings.TrimSpace(sql), migrationNoTx) {
+		if _, err := conn.Exec(ctx, sql); err != nil {
+			return err
+		}
+		_, err := conn.Exec(ctx, record, args...)
+		return err
+	}
+	return pgx.BeginFunc(ctx, conn, func(tx pgx.Tx) error {
+		if _, err := tx.Exec(ctx, sql); err != nil {
+			return err
+		}
+		_, err := tx.Exec(ctx, record, args...)
+		return err
+	})
+}
+
+var migrators struct {
+	mu   sync.Mutex
+	list []*Migrator
+}
+
+// RegisterMigrator 注册迁移：启动时以 PreStart 钩子执行 Up（SkipOnStart 时跳过，失败中止启动），
+// 并在管理端口 /migrations 输出状态。钩子会先等待对应的 Postgres 实例就绪。
+func RegisterMigrator(m *Migrator) {
+	migrators.mu.Lock()
+	migrators.list = append(migrators.list, m)
+	migrators.mu.Unlock()
+	if m.SkipOnStart {
+		return
+	}
+	timeout := m.Timeout
+	if timeout <= 0 {
+		timeout = 5 * time.Minute
+	}
+	RegisterHook(Hook{
+		Name:    "migrate:" + m.table(),
+		Phase:   PreStart,
+		Timeout: timeout,
+		Fn: func(ctx context.Context) error {
+			dep := "postgres"
+			if m.DB != "" {
+				dep += ":" + strings.ToLower(m.DB)
+			}
+			// 等待依赖同样计入迁移的 Timeout，而不是 STARTUP_WAIT_TIMEOUT。
+			if err := waitForDependencies(ctx, []string{dep}, timeout); err != nil {
+				return err
+			}
+			_, err := m.Up(ctx)
+			return err
+		},
+	})
+}
+
+// MigrationReport 一个已注册 Migrator 的状态，供 /migrations 输出。
+type MigrationReport struct {
+	DB         string            `json:"db"`
+	Table      string            `json:"table"`
+	Migrations []MigrationStatus `json:"migrations"`
+	Error      string            `json:"error,omitempty"`
+}
+
+// MigrationsStatus 返回所有 RegisterMigrator 注册的迁移状态，查询失败的记在 Error 中。
+func MigrationsStatus(ctx context.Context) []MigrationReport {
+	migrators.mu.Lock()
+	list := slices.Clone(migrators.list)
+	migrators.mu.Unlock()
+	reports := make([]MigrationReport, 0, len(list))
+	for _, m := range list {
+		r := MigrationReport{DB: m.DB, Table: m.table()}
+		status, err := m.Status(ctx)
+		if err != nil {
+			r.Error = err.Error()
+		}
+		r.Migrations = status
+		reports = append(reports, r)
+	}
+	return reports
+}
@@ -0,0 +1,90 @@
+package gowk
+
+import (
+	"context"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+)
+
+func TestMigratorLoad(t *testing.T) {
+	fsys := fstest.MapFS{
+		"migrations/0002_add_email.up.sql":   {Data: []byte("ALTER TABLE users ADD email text;")},
+		"migrations/0002_add_email.down.sql": {Data: []byte("ALTER TABLE users DROP email;")},
+		"migrations/0001_init.sql":           {Data: []byte("CREATE TABLE users (id bigint);")},
+		"migrations/README.md":               {Data: []byte("ignored")},
+	}
+	migrations, err := NewMigrator(fsys, "migrations").load()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(migrations) != 2 || migrations[0].name != "init" || migrations[0].hasDown || migrations[1].version != 2 || !migrations[1].hasDown {
+		t.Fatalf("migrations = %+v", migrations)
+	}
+
+	cases := map[string]fstest.MapFS{
+		"不合法":      {"init.sql": {}},
+		"重复":       {"1_a.up.sql": {Data: []byte("x")}, "1_b.up.sql": {Data: []byte("y")}},
+		"缺少 up 文件": {"1_a.down.sql": {Data: []byte("x")}},
+	}
+	for want, fsys := range cases {
+		if _, err := NewMigrator(fsys, "").load(); err == nil || !strings.Contains(err.Error(), want) {
+			t.Errorf("want error containing %q, got %v", want, err)
+		}
+	}
+}
+
+func TestMigrationStatus(t *testing.T) {
+	now := time.Now()
+	migrations := []migration{{version: 1, name: "init"}, {version: 3, name: "index", hasDown: true}}
+	applied := map[int64]appliedMigration{1: {name: "init", appliedAt: now}, 2: {name: "removed", appliedAt: now}}
+	status := migrationStatus(migrations, applied)
+	if len(status) != 3 {
+		t.Fatalf("status = %+v", status)
+	}
+	if !status[0].Applied || status[1].Version != 2 || !status[1].Missing || status[2].Applied || !status[2].HasDown {
+		t.Fatalf("status = %+v", status)
+	}
+
+	m := &Migrator{Table: "app.schema_migrations"}
+	if m.tableIdent() != `"app"."schema_migrations"` || m.lockKey() == (&Migrator{}).lockKey() {
+		t.Fatalf("table = %s", m.tableIdent())
+	}
+	if _, err := NewMigrator(fstest.MapFS{}, "").Up(context.Background()); err == nil {
+		t.Fatal("Up without postgres should fail")
+	}
+}
+
+func TestMigrationPlan(t *testing.T) {
+	migrations := []migration{
+		{version: 1, name: "init", hasDown: true},
+		{version: 2, name: "users"},
+		{version: 3, name: "email", hasDown: true},
+		{version: 4, name: "index", hasDown: true},
+	}
+	versions := func(list []migration) []int64 {
+		var vs []int64
+		for _, mg := range list {
+			vs = append(vs, mg.version)
+		}
+		return vs
+	}
+	applied := map[int64]appliedMigration{1: {}, 3: {}}
+	if got := versions(planUp(migrations, applied)); !slices.Equal(got, []int64{2, 4}) {
+		t.Fatalf("up = %v", got)
+	}
+
+	applied = map[int64]appliedMigration{1: {}, 2: {}, 3: {}, 4: {}}
+	down, err := planDown(migrations, applied, 2)
+	if err != nil || !slices.Equal(versions(down), []int64{4, 3}) {
+		t.Fatalf("down = %v %v", versions(down), err)
+	}
+	if down, _ := planDown(migrations, applied, 0); !slices.Equal(versions(down), []int64{4}) {
+		t.Fatalf("down 0 steps = %v", versions(down))
+	}
+	if _, err := planDown(migrations, applied, 3); err == nil || !strings.Contains(err.Error(), "2_users 没有 down 文件") {
+		t.Fatalf("missing down: %v", err)
+	}
+}
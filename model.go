@@ -11,12 +11,29 @@ type PageModel[T any] struct {
 	Pages   int64 `json:"pages"`
 	Total   int64 `json:"total"`
 	Records []*T  `json:"records"`
+	// Query 过滤与排序条件，Handler.Page 从查询参数解析，见 QuerySpec。
+	Query QuerySpec `json:"-" form:"-"`
+}
+
+// Normalize 修正分页参数：Size <= 0 时取 PAGE_DEFAULT_SIZE，超过 PAGE_MAX_SIZE 时截断为最大值；Current <= 0 时为 1。
+func (p *PageModel[T]) Normalize() {
+	p.Size = normalizePageSize(p.Size)
+	if p.Current <= 0 {
+		p.Current = 1
+	}
+}
+
+func normalizePageSize(size int64) int64 {
+	if size <= 0 {
+		size = conf().Page.DefaultSize
+	}
+	return min(size, conf().Page.MaxSize)
 }
 
 // CalcPages 根据已设置的 Total 和 Size 计算总页数，应在 COUNT 查询之后调用。
 func (p *PageModel[T]) CalcPages() {
 	if p.Size <= 0 {
-		p.Size = 10
+		p.Size = conf().Page.DefaultSize
 	}
 	p.Pages = p.Total / p.Size
 	if p.Total%p.Size != 0 {
@@ -24,5 +41,27 @@ func (p *PageModel[T]) CalcPages() {
 	}
 }
 
+// CursorPage 游标（keyset）分页：按排序字段与主键定位上一页的边界，不做 OFFSET，默认也不做 COUNT，
+// 适合大表和无限滚动。Cursor 为请求参数，取自上一次响应的 NextCursor / PrevCursor，为空时返回第一页；
+// Next / Prev 为 Handler 生成的下一页 / 上一页链接，没有更多数据时为空。见 Repository.CursorPage。
+type CursorPage[T any] struct {
+	Size   int64  `json:"size" form:"size"`
+	Cursor string `json:"-" form:"cursor"`
+	// WithTotal 为 true（查询参数 total=true）时额外 COUNT 符合条件的总数，写入 Total。
+	WithTotal  bool      `json:"-" form:"total"`
+	Total      *int64    `json:"total,omitempty" form:"-"`
+	NextCursor string    `json:"nextCursor,omitempty" form:"-"`
+	PrevCursor string    `json:"prevCursor,omitempty" form:"-"`
+	Next       string    `json:"next,omitempty" form:"-"`
+	Prev       string    `json:"prev,omitempty" form:"-"`
+	Records    []*T      `json:"records" form:"-"`
+	Query      QuerySpec `json:"-" form:"-"`
+}
+
+// Normalize 修正 Size，规则同 PageModel.Normalize。
+func (p *CursorPage[T]) Normalize() {
+	p.Size = normalizePageSize(p.Size)
+}
+
 type M = map[string]interface{}
 type A = []interface{}
@@ -3,7 +3,10 @@ package gowk
 import (
 	"context"
 	"errors"
+	"fmt"
 	"log/slog"
+	"slices"
+	"strings"
 	"sync"
 	"sync/atomic"
 
@@ -12,89 +15,259 @@ import (
 	"github.com/jackc/pgx/v5/tracelog"
 )
 
+// pgInstance 一个 Postgres 连接池，各自独立地后台重试、探活与关闭。
+// 默认实例对应 DATABASE_DSN；命名实例对应 DATABASE_DSN_<NAME>（配置文件中为 database.named.<name>）；
+// 只读副本对应 DATABASE_REPLICA_DSNS 中的一项，见 postgres_replica.go。
+type pgInstance struct {
+	name   string
+	dsn    func() string
+	pool   atomic.Pointer[pgxpool.Pool]
+	health *healthTracker
+	once   sync.Once
+	cancel context.CancelFunc
+}
+
 var (
-	defaultPostgres atomic.Pointer[pgxpool.Pool]
-	pgInitOnce      sync.Once
-	pgRetryCancel   context.CancelFunc
+	defaultPostgres = &pgInstance{dsn: func() string { return conf().Database.DSN }, health: pgHealth}
+	// namedPostgres 已创建的命名实例，键为小写名字。
+	namedPostgres struct {
+		mu        sync.Mutex
+		instances map[string]*pgInstance
+	}
 )
 
-func initPostgres() {
+// label 日志中的实例名，默认实例为 "PostgreSQL"。
+func (p *pgInstance) label() string {
+	if p.name == "" {
+		return "PostgreSQL"
+	}
+	return "PostgreSQL[" + p.name + "]"
+}
+
+// get 返回连接池，首次调用触发 init；未连上时为 nil。
+func (p *pgInstance) get() *pgxpool.Pool {
+	p.once.Do(p.init)
+	return p.pool.Load()
+}
+
+func (p *pgInstance) init() {
 	// DSN 未配置属于"没启用"，保持老行为直接返回，
 	// 业务侧靠 Postgres(ctx) == nil / PostgresTx 的错误判断降级。
-	if databaseDsn == "" {
+	dsn := p.dsn()
+	if dsn == "" {
 		return
 	}
-	pgxConfig, err := pgxpool.ParseConfig(databaseDsn)
+	pgxConfig, err := pgxpool.ParseConfig(dsn)
 	if err != nil {
 		// DSN 语法错误后台再怎么重试也是同一个错，直接降级并记一条错误，避免刷屏。
-		slog.Error("PostgreSQL 配置解析异常，保持降级", "err", err)
+		slog.Error(p.label()+" 配置解析异常，保持降级", "err", err)
+		p.health.set(HealthDisabled, err)
 		return
 	}
 	pgxConfig.ConnConfig.Tracer = &tracelog.TraceLog{
 		Logger:   &PostgresLogger{},
 		LogLevel: tracelog.LogLevelDebug,
 	}
+	applyPoolConfig(pgxConfig, conf().Database.Pool)
 
 	ctx, cancel := context.WithCancel(context.Background())
-	pgRetryCancel = cancel
-	go retryBackground(ctx, "PostgreSQL", pgRetryBaseInterval, pgRetryMaxInterval, func(c context.Context) error {
-		pingCtx, cancelPing := context.WithTimeout(c, pgPingTimeout)
+	p.cancel = cancel
+	p.health.set(HealthConnecting, nil)
+	go func() {
+		retryBackground(ctx, p.label(), conf().Database.RetryBaseInterval, conf().Database.RetryMaxInterval, p.health.observe, p.connect(pgxConfig))
+		// 连上之后转入周期探活，让运行期断线反映到 /readyz。
+		if pool := p.pool.Load(); pool != nil {
+			go logPoolStats(ctx, p, conf().Database.Pool.StatsLogInterval)
+			monitorBackground(ctx, p.health, conf().Health.Interval, conf().Database.PingTimeout, pool.Ping)
+		}
+	}()
+}
+
+// connect 返回一次连接尝试：建池并 Ping 成功后发布到 p.pool。
+func (p *pgInstance) connect(pgxConfig *pgxpool.Config) func(context.Context) error {
+	return func(c context.Context) error {
+		pingCtx, cancelPing := context.WithTimeout(c, conf().Database.PingTimeout)
 		defer cancelPing()
-		p, err := pgxpool.NewWithConfig(pingCtx, pgxConfig)
+		pool, err := pgxpool.NewWithConfig(pingCtx, pgxConfig)
 		if err != nil {
 			// 防御未来 pgx 实现变化：同时返回 pool 与 err 时也要释放。
-			if p != nil {
-				p.Close()
+			if pool != nil {
+				pool.Close()
 			}
 			return err
 		}
-		if err := p.Ping(pingCtx); err != nil {
-			p.Close()
+		if err := pool.Ping(pingCtx); err != nil {
+			pool.Close()
 			return err
 		}
-		defaultPostgres.Store(p)
+		p.pool.Store(pool)
 		return nil
-	})
+	}
 }
 
-func closePostgres() {
-	if pgRetryCancel != nil {
-		pgRetryCancel()
+// queryExecModes StatementCacheMode 的可选值，与 DSN 参数 default_query_exec_mode 一致。
+var queryExecModes = map[string]pgx.QueryExecMode{
+	"cache_statement": pgx.QueryExecModeCacheStatement,
+	"cache_describe":  pgx.QueryExecModeCacheDescribe,
+	"describe_exec":   pgx.QueryExecModeDescribeExec,
+	"exec":            pgx.QueryExecModeExec,
+	"simple_protocol": pgx.QueryExecModeSimpleProtocol,
+}
+
+// applyPoolConfig 用 PoolConfig 中已配置的项覆盖 DSN 解析出的连接池参数。
+func applyPoolConfig(c *pgxpool.Config, pc PoolConfig) {
+	if pc.MaxConns > 0 {
+		c.MaxConns = pc.MaxConns
 	}
-	if pool := defaultPostgres.Load(); pool != nil {
+	if pc.MinConns > 0 {
+		c.MinConns = pc.MinConns
+	}
+	if c.MinConns > c.MaxConns {
+		slog.Warn("DATABASE_MIN_CONNS 大于最大连接数，按最大连接数处理", "min", c.MinConns, "max", c.MaxConns)
+		c.MinConns = c.MaxConns
+	}
+	if pc.MaxConnLifetime > 0 {
+		c.MaxConnLifetime = pc.MaxConnLifetime
+	}
+	if pc.MaxConnIdleTime > 0 {
+		c.MaxConnIdleTime = pc.MaxConnIdleTime
+	}
+	if pc.HealthCheckPeriod > 0 {
+		c.HealthCheckPeriod = pc.HealthCheckPeriod
+	}
+	if mode, ok := queryExecModes[pc.StatementCacheMode]; ok {
+		c.ConnConfig.DefaultQueryExecMode = mode
+	}
+}
+
+// close 停止后台重试与探活并关闭连接池。
+func (p *pgInstance) close() {
+	if p.cancel != nil {
+		p.cancel()
+	}
+	if pool := p.pool.Load(); pool != nil {
 		pool.Close()
 	}
 }
 
+// postgresInstance 返回名为 name 的实例，"" 为默认实例；name 不区分大小写，
+// 未在配置中出现的名字返回 nil。
+func postgresInstance(name string) *pgInstance {
+	name = strings.ToLower(name)
+	if name == "" {
+		return defaultPostgres
+	}
+	namedPostgres.mu.Lock()
+	defer namedPostgres.mu.Unlock()
+	if p, ok := namedPostgres.instances[name]; ok {
+		return p
+	}
+	if _, ok := conf().Database.Named[name]; !ok {
+		return nil
+	}
+	if namedPostgres.instances == nil {
+		namedPostgres.instances = map[string]*pgInstance{}
+	}
+	p := &pgInstance{
+		name:   name,
+		dsn:    func() string { return conf().Database.Named[name] },
+		health: newHealthTracker("postgres:" + name),
+	}
+	namedPostgres.instances[name] = p
+	return p
+}
+
+// namedPostgresInstances 按名字排序返回已创建的命名实例。
+func namedPostgresInstances() []*pgInstance {
+	namedPostgres.mu.Lock()
+	defer namedPostgres.mu.Unlock()
+	names := make([]string, 0, len(namedPostgres.instances))
+	for name := range namedPostgres.instances {
+		names = append(names, name)
+	}
+	slices.Sort(names)
+	instances := make([]*pgInstance, len(names))
+	for i, name := range names {
+		instances[i] = namedPostgres.instances[name]
+	}
+	return instances
+}
+
+func closePostgres() {
+	defaultPostgres.close()
+	for _, p := range namedPostgresInstances() {
+		p.close()
+	}
+	for _, p := range postgresReplicas() {
+		p.close()
+	}
+}
+
+// PostgresTx 返回默认实例上的事务，见 PostgresNamedTx。
 func PostgresTx(ctx context.Context) (pgx.Tx, error) {
-	if tx, ok := ctx.Value(TRANSACTION).(*Transaction); ok && tx != nil {
-		if tx.Tx != nil {
-			return tx.Tx, nil
-		}
-		if tx.Begin {
-			pool := Postgres(ctx)
-			if pool == nil {
-				return nil, errors.New("postgres unavailable")
-			}
-			pgTx, err := pool.Begin(ctx)
-			if err != nil {
-				return nil, err
-			}
-			tx.Tx = pgTx
-			return tx.Tx, nil
+	return PostgresNamedTx(ctx, "")
+}
+
+// PostgresNamedTx 返回 ctx 中 Transaction 在名为 name 的实例上的事务，Begin 之后首次调用时开启。
+// 同一请求可以在多个实例上各开一个事务，Commit / Rollback 会依次处理，但跨库并不是原子的。
+func PostgresNamedTx(ctx context.Context, name string) (pgx.Tx, error) {
+	tx, ok := ctx.Value(TRANSACTION).(*Transaction)
+	if !ok || tx == nil {
+		return nil, errors.New("no tx")
+	}
+	name = strings.ToLower(name)
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	if t := tx.get(name); t != nil {
+		return t, nil
+	}
+	if !tx.Begin {
+		return nil, errors.New("no tx")
+	}
+	// 事务总是在主库上开启，不受 ReadOnly 路由影响。
+	var pool *pgxpool.Pool
+	if p := postgresInstance(name); p != nil {
+		pool = p.get()
+	}
+	if pool == nil {
+		if name == "" {
+			return nil, errors.New("postgres unavailable")
 		}
+		return nil, fmt.Errorf("postgres %s unavailable", name)
+	}
+	pgTx, err := pool.Begin(ctx)
+	if err != nil {
+		return nil, err
 	}
-	return nil, errors.New("no tx")
+	tx.set(name, pgTx)
+	return pgTx, nil
 }
 
-// Postgres 返回连接池，通过 sync.Once 保证 initPostgres 只触发一次后台重试。
+// Postgres 返回连接池，通过 sync.Once 保证初始化只触发一次后台重试。
 // 返回值语义：
 //   - DATABASE_DSN 未配置 / 配置错误 / 后台尚未连上 → nil
 //   - 后台连接成功后 → 非 nil 连接池
 //
 // 调用方应在业务层检查 nil 并返回错误（例如 PostgresTx 的 "postgres unavailable"），
 // 不要对 nil 池直接调用方法。
+//
+// ctx 经 ReadOnly / WithReadOnly 标记为只读、且不在事务中时，返回一个就绪的只读副本；
+// 未配置副本或副本全部不可用时回落到主库。
 func Postgres(ctx context.Context) *pgxpool.Pool {
-	pgInitOnce.Do(initPostgres)
-	return defaultPostgres.Load()
+	if isReadOnly(ctx) && !inTransaction(ctx) {
+		if pool := replicaPool(); pool != nil {
+			return pool
+		}
+	}
+	return defaultPostgres.get()
+}
+
+// PostgresNamed 返回命名实例的连接池，如 DATABASE_DSN_REPORTING 对应 PostgresNamed(ctx, "reporting")，
+// name 为空时等同 Postgres。返回值语义同 Postgres，未配置的名字同样返回 nil；命名实例不做只读副本路由。
+func PostgresNamed(ctx context.Context, name string) *pgxpool.Pool {
+	p := postgresInstance(name)
+	if p == nil {
+		return nil
+	}
+	return p.get()
 }
@@ -0,0 +1,90 @@
+package gowk
+
+import (
+	"context"
+	"strconv"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// READ_ONLY 标记只读请求的 key，gin.Context 与 context.Context 通用。
+const READ_ONLY = "readOnly"
+
+// ReadOnly 把请求标记为只读，之后 Postgres(ctx) 优先返回只读副本。
+// 适合列表、详情等可以容忍复制延迟的读接口，写后立即读的场景不要使用。
+func ReadOnly() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.Set(READ_ONLY, true)
+		ctx.Next()
+	}
+}
+
+// WithReadOnly 返回标记为只读的 ctx，供非 HTTP 场景（gRPC、任务）使用，语义同 ReadOnly。
+func WithReadOnly(ctx context.Context) context.Context {
+	return context.WithValue(ctx, READ_ONLY, true)
+}
+
+func isReadOnly(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	v, _ := ctx.Value(READ_ONLY).(bool)
+	return v
+}
+
+// inTransaction 请求是否已调用 Begin 或已开启事务，此时查询一律走主库，避免读到事务外的旧数据。
+func inTransaction(ctx context.Context) bool {
+	if ctx == nil {
+		return false
+	}
+	tx, ok := ctx.Value(TRANSACTION).(*Transaction)
+	if !ok || tx == nil {
+		return false
+	}
+	tx.mu.Lock()
+	defer tx.mu.Unlock()
+	return tx.Begin || tx.started()
+}
+
+// replicas 只读副本实例，首次使用时按配置创建，各自后台重试与探活，健康状态名为 postgres-replica-<n>（从 1 开始）。
+var replicas struct {
+	once      sync.Once
+	instances []*pgInstance
+	next      atomic.Uint64
+}
+
+func postgresReplicas() []*pgInstance {
+	replicas.once.Do(func() {
+		for i, dsn := range conf().Database.Replicas {
+			name := "replica-" + strconv.Itoa(i+1)
+			replicas.instances = append(replicas.instances, &pgInstance{
+				name:   name,
+				dsn:    func() string { return dsn },
+				health: &healthTracker{name: "postgres-" + name, since: time.Now(), optional: true},
+			})
+		}
+	})
+	return replicas.instances
+}
+
+// replicaPool 轮询返回一个探活就绪的副本，全部不可用时返回 nil。
+func replicaPool() *pgxpool.Pool {
+	instances := postgresReplicas()
+	n := uint64(len(instances))
+	if n == 0 {
+		return nil
+	}
+	start := replicas.next.Add(1)
+	for i := range n {
+		p := instances[(start+i)%n]
+		pool := p.get()
+		if pool != nil && p.health.snapshot().State == HealthReady {
+			return pool
+		}
+	}
+	return nil
+}
@@ -0,0 +1,68 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// testReplica 返回一个已"就绪"的副本实例，连接池是惰性的，不会真正连库。
+func testReplica(t *testing.T, name string) *pgInstance {
+	pool, err := pgxpool.New(context.Background(), "postgres://app@127.0.0.1:1/app")
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(pool.Close)
+	p := &pgInstance{name: name, health: &healthTracker{name: "postgres-" + name, optional: true}}
+	p.once.Do(func() {})
+	p.pool.Store(pool)
+	p.health.set(HealthReady, nil)
+	return p
+}
+
+func TestReplicaRouting(t *testing.T) {
+	prev := postgresReplicas()
+	t.Cleanup(func() { replicas.instances = prev })
+	r1, r2 := testReplica(t, "replica-1"), testReplica(t, "replica-2")
+	replicas.instances = []*pgInstance{r1, r2}
+	r2.health.set(HealthLost, errors.New("down"))
+
+	ctx := WithReadOnly(context.Background())
+	for range 3 {
+		if Postgres(ctx) != r1.pool.Load() {
+			t.Fatal("read-only ctx should use the ready replica")
+		}
+	}
+	primary := defaultPostgres.get()
+	if Postgres(context.Background()) != primary {
+		t.Fatal("plain ctx should use the primary")
+	}
+	txCtx := context.WithValue(ctx, TRANSACTION, &Transaction{Begin: true})
+	if Postgres(txCtx) != primary {
+		t.Fatal("transaction should use the primary")
+	}
+
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.GET("/list", ReadOnly(), func(c *gin.Context) {
+		if Postgres(c) != r1.pool.Load() {
+			t.Error("ReadOnly middleware should route to the replica")
+		}
+	})
+	engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/list", nil))
+
+	r1.health.set(HealthLost, errors.New("down"))
+	if Postgres(ctx) != primary {
+		t.Fatal("should fall back to the primary when all replicas are down")
+	}
+	for _, d := range CheckHealth(context.Background()).Dependencies {
+		if d.Name == "postgres-replica-1" && !d.Optional {
+			t.Fatal("replica should be optional")
+		}
+	}
+}
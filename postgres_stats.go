@@ -0,0 +1,122 @@
+package gowk
+
+import (
+	"context"
+	"log/slog"
+	"time"
+
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+// PostgresPoolStats 单个连接池的统计快照，累计值（*Count / *Duration）自连接池创建起计。
+type PostgresPoolStats struct {
+	// Name 与 /readyz 中的依赖名一致：postgres、postgres:<name>、postgres-replica-<n>。
+	Name              string `json:"name"`
+	MaxConns          int32  `json:"maxConns"`
+	TotalConns        int32  `json:"totalConns"`
+	AcquiredConns     int32  `json:"acquiredConns"`
+	IdleConns         int32  `json:"idleConns"`
+	ConstructingConns int32  `json:"constructingConns"`
+	AcquireCount      int64  `json:"acquireCount"`
+	// AcquireDuration 所有成功获取连接的累计耗时。
+	AcquireDuration time.Duration `json:"acquireDuration"`
+	// EmptyAcquireCount / EmptyAcquireWaitTime 获取时池中没有空闲连接、需要等待的次数与累计等待时间。
+	EmptyAcquireCount       int64         `json:"emptyAcquireCount"`
+	EmptyAcquireWaitTime    time.Duration `json:"emptyAcquireWaitTime"`
+	CanceledAcquireCount    int64         `json:"canceledAcquireCount"`
+	NewConnsCount           int64         `json:"newConnsCount"`
+	MaxLifetimeDestroyCount int64         `json:"maxLifetimeDestroyCount"`
+	MaxIdleDestroyCount     int64         `json:"maxIdleDestroyCount"`
+}
+
+// Saturation 已借出连接占最大连接数的比例，1 表示连接池已耗尽。
+func (s PostgresPoolStats) Saturation() float64 {
+	if s.MaxConns == 0 {
+		return 0
+	}
+	return float64(s.AcquiredConns) / float64(s.MaxConns)
+}
+
+func poolStats(name string, pool *pgxpool.Pool) PostgresPoolStats {
+	st := pool.Stat()
+	return PostgresPoolStats{
+		Name:                    name,
+		MaxConns:                st.MaxConns(),
+		TotalConns:              st.TotalConns(),
+		AcquiredConns:           st.AcquiredConns(),
+		IdleConns:               st.IdleConns(),
+		ConstructingConns:       st.ConstructingConns(),
+		AcquireCount:            st.AcquireCount(),
+		AcquireDuration:         st.AcquireDuration(),
+		EmptyAcquireCount:       st.EmptyAcquireCount(),
+		EmptyAcquireWaitTime:    st.EmptyAcquireWaitTime(),
+		CanceledAcquireCount:    st.CanceledAcquireCount(),
+		NewConnsCount:           st.NewConnsCount(),
+		MaxLifetimeDestroyCount: st.MaxLifetimeDestroyCount(),
+		MaxIdleDestroyCount:     st.MaxIdleDestroyCount(),
+	}
+}
+
+// PostgresStats 返回所有已连上的连接池（默认实例、命名实例、只读副本）的统计，未启用 / 未就绪的不输出。
+func PostgresStats() []PostgresPoolStats {
+	var stats []PostgresPoolStats
+	instances := append([]*pgInstance{defaultPostgres}, namedPostgresInstances()...)
+	for _, p := range append(instances, postgresReplicas()...) {
+		if pool := p.pool.Load(); pool != nil {
+			stats = append(stats, poolStats(p.health.name, pool))
+		}
+	}
+	return stats
+}
+
+// logPoolStats 按 interval 输出连接池在这段时间内的获取次数、平均等待与饱和度；
+// 连接池耗尽（借出数达到上限）或出现获取超时取消时以 Warn 输出。interval <= 0 时不输出。
+func logPoolStats(ctx context.Context, p *pgInstance, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	var last PostgresPoolStats
+	if pool := p.pool.Load(); pool != nil {
+		last = poolStats(p.health.name, pool)
+	}
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		pool := p.pool.Load()
+		if pool == nil {
+			continue
+		}
+		cur := poolStats(p.health.name, pool)
+		acquires := cur.AcquireCount - last.AcquireCount
+		waits := cur.EmptyAcquireCount - last.EmptyAcquireCount
+		canceled := cur.CanceledAcquireCount - last.CanceledAcquireCount
+		var avgAcquire, avgWait time.Duration
+		if acquires > 0 {
+			avgAcquire = (cur.AcquireDuration - last.AcquireDuration) / time.Duration(acquires)
+		}
+		if waits > 0 {
+			avgWait = (cur.EmptyAcquireWaitTime - last.EmptyAcquireWaitTime) / time.Duration(waits)
+		}
+		level, msg := slog.LevelInfo, "连接池统计"
+		if cur.AcquiredConns >= cur.MaxConns || canceled > 0 {
+			level, msg = slog.LevelWarn, "连接池已耗尽或有获取被取消"
+		}
+		slog.Log(ctx, level, p.label()+" "+msg,
+			"acquired", cur.AcquiredConns,
+			"total", cur.TotalConns,
+			"max", cur.MaxConns,
+			"saturation", cur.Saturation(),
+			"acquires", acquires,
+			"avgAcquire", avgAcquire,
+			"waits", waits,
+			"avgWait", avgWait,
+			"canceled", canceled,
+		)
+		last = cur
+	}
+}
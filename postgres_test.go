@@ -0,0 +1,97 @@
+package gowk
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgxpool"
+)
+
+func TestNamedPostgres(t *testing.T) {
+	keepConfig(t)
+	t.Cleanup(func() {
+		namedPostgres.mu.Lock()
+		namedPostgres.instances = nil
+		namedPostgres.mu.Unlock()
+	})
+	secret := filepath.Join(t.TempDir(), "audit_dsn")
+	os.WriteFile(secret, []byte("postgres://audit:pw@db/audit\n"), 0o600)
+	// 解析失败的 DSN 直接降级，不会启动后台重试。
+	t.Setenv("DATABASE_DSN_REPORTING", "postgres://app:hunter2@db:bad-port/reporting")
+	t.Setenv("DATABASE_DSN_AUDIT_FILE", secret)
+
+	var cfg testAppConfig
+	if err := LoadConfig(&cfg); err != nil {
+		t.Fatal(err)
+	}
+	named := conf().Database.Named
+	if len(named) != 2 || named["audit"] != "postgres://audit:pw@db/audit" || !strings.HasSuffix(named["reporting"], "/reporting") {
+		t.Fatalf("named dsn = %v", named)
+	}
+	for _, e := range ConfigDump() {
+		if e.Key == "gowk.database.named.reporting" && (e.Value != "postgres://app:******@db:bad-port/reporting" || e.From != "DATABASE_DSN_REPORTING") {
+			t.Errorf("dump entry = %+v", e)
+		}
+	}
+
+	if PostgresNamed(context.Background(), "unknown") != nil {
+		t.Fatal("unknown name should return nil")
+	}
+	if PostgresNamed(context.Background(), "Reporting") != nil {
+		t.Fatal("invalid dsn should return nil")
+	}
+	if _, err := dependencyReady(context.Background(), "postgres:reporting"); err == nil || !strings.Contains(err.Error(), "配置无效") {
+		t.Fatalf("dependencyReady = %v", err)
+	}
+	if _, err := dependencyReady(context.Background(), "postgres:unknown"); err == nil {
+		t.Fatal("unknown instance should not be waited for")
+	}
+	var names []string
+	for _, d := range HealthStatus() {
+		names = append(names, d.Name)
+	}
+	if strings.Join(names, ",") != "postgres,postgres:reporting,redis" {
+		t.Fatalf("health names = %v", names)
+	}
+
+	ctx := context.WithValue(context.Background(), TRANSACTION, &Transaction{Begin: true})
+	if _, err := PostgresNamedTx(ctx, "reporting"); err == nil || err.Error() != "postgres reporting unavailable" {
+		t.Fatalf("PostgresNamedTx = %v", err)
+	}
+}
+
+func TestApplyPoolConfig(t *testing.T) {
+	c, err := pgxpool.ParseConfig("postgres://app@db/app?pool_max_conns=4&pool_min_conns=2")
+	if err != nil {
+		t.Fatal(err)
+	}
+	applyPoolConfig(c, PoolConfig{MaxConnLifetime: time.Minute, StatementCacheMode: "exec"})
+	if c.MaxConns != 4 || c.MinConns != 2 || c.MaxConnLifetime != time.Minute || c.ConnConfig.DefaultQueryExecMode != pgx.QueryExecModeExec {
+		t.Fatalf("dsn params should be kept: %+v", c)
+	}
+	applyPoolConfig(c, PoolConfig{MaxConns: 1, HealthCheckPeriod: time.Second})
+	if c.MaxConns != 1 || c.MinConns != 1 || c.HealthCheckPeriod != time.Second {
+		t.Fatalf("options should override dsn: max=%d min=%d", c.MaxConns, c.MinConns)
+	}
+}
+
+func TestPostgresStats(t *testing.T) {
+	prev := postgresReplicas()
+	t.Cleanup(func() { replicas.instances = prev })
+	replicas.instances = []*pgInstance{testReplica(t, "replica-1")}
+
+	var got *PostgresPoolStats
+	for _, st := range PostgresStats() {
+		if st.Name == "postgres-replica-1" {
+			got = &st
+		}
+	}
+	if got == nil || got.MaxConns == 0 || got.Saturation() != 0 {
+		t.Fatalf("stats = %+v", got)
+	}
+}
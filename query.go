@@ -0,0 +1,215 @@
+package gowk
+
+import (
+	"fmt"
+	"net/url"
+	"reflect"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/jackc/pgx/v5"
+)
+
+// FilterOp 过滤操作。
+type FilterOp string
+
+const (
+	FilterEq   FilterOp = "eq"
+	FilterNe   FilterOp = "ne"
+	FilterGt   FilterOp = "gt"
+	FilterGte  FilterOp = "gte"
+	FilterLt   FilterOp = "lt"
+	FilterLte  FilterOp = "lte"
+	FilterIn   FilterOp = "in"
+	FilterNin  FilterOp = "nin"
+	FilterLike FilterOp = "like"
+	// FilterNull 值为 true 时 IS NULL，false 时 IS NOT NULL。
+	FilterNull FilterOp = "null"
+)
+
+// filterOperators 各操作对应的 SQL 运算符，in / nin 的值按逗号拆成数组。
+var filterOperators = map[FilterOp]string{
+	FilterEq:   "=",
+	FilterNe:   "<>",
+	FilterGt:   ">",
+	FilterGte:  ">=",
+	FilterLt:   "<",
+	FilterLte:  "<=",
+	FilterIn:   "= ANY",
+	FilterNin:  "<> ALL",
+	FilterLike: "LIKE",
+	FilterNull: "IS NULL",
+}
+
+// Filter 单个过滤条件，Field 为字段的查询名（json tag 名，没有时为列名），Value 为原始字符串。
+type Filter struct {
+	Field string
+	Op    FilterOp
+	Value string
+}
+
+// Sort 单个排序字段。
+type Sort struct {
+	Field string
+	Desc  bool
+}
+
+// QuerySpec 列表查询的过滤与排序条件，由 ParseQuerySpec 从查询参数解析，Repository.Page 编译为参数化 SQL。
+// 字段必须在 T 上通过 tag 显式放开：
+//
+//	Age       int       `db:"age" json:"age" filter:"eq,gte,lte,in" sort:"true"`
+//	CreatedAt time.Time `db:"created_at" json:"createdAt" filter:"gte,lt" sort:"true"`
+//
+// 对应的查询参数：?filter[age][gte]=18&filter[age][lte]=60&filter[age][in]=18,20&sort=-createdAt,age，
+// filter[age]=18 等同 filter[age][eq]=18，多个条件之间为 AND。
+type QuerySpec struct {
+	Filters []Filter
+	Sorts   []Sort
+}
+
+var filterParamPattern = regexp.MustCompile(`^filter\[([^\[\]]+)\](?:\[([^\[\]]+)\])?$`)
+
+// ParseQuerySpec 从查询参数解析 T 的过滤与排序条件，字段未放开、操作不允许或值无法转换为字段类型时返回 ERR_PARAM 类错误。
+func ParseQuerySpec[T any](values url.Values) (QuerySpec, error) {
+	var spec QuerySpec
+	keys := make([]string, 0, len(values))
+	for key := range values {
+		keys = append(keys, key)
+	}
+	slices.Sort(keys)
+	for _, key := range keys {
+		match := filterParamPattern.FindStringSubmatch(key)
+		if match == nil {
+			continue
+		}
+		op := FilterOp(match[2])
+		if op == "" {
+			op = FilterEq
+		}
+		for _, v := range values[key] {
+			spec.Filters = append(spec.Filters, Filter{Field: match[1], Op: op, Value: v})
+		}
+	}
+	for _, v := range values["sort"] {
+		for _, field := range strings.Split(v, ",") {
+			field = strings.TrimSpace(field)
+			if field == "" {
+				continue
+			}
+			s := Sort{Field: field}
+			if f, ok := strings.CutPrefix(field, "-"); ok {
+				s = Sort{Field: f, Desc: true}
+			} else {
+				s.Field = strings.TrimPrefix(field, "+")
+			}
+			spec.Sorts = append(spec.Sorts, s)
+		}
+	}
+	if len(spec.Filters) == 0 && len(spec.Sorts) == 0 {
+		return spec, nil
+	}
+	meta, err := metaOf[T]()
+	if err != nil {
+		return spec, err
+	}
+	if _, _, err := meta.filters(spec.Filters, nil); err != nil {
+		return spec, err
+	}
+	if _, err := meta.orderBy(spec.Sorts); err != nil {
+		return spec, err
+	}
+	return spec, nil
+}
+
+func queryParamError(format string, a ...any) *ErrorCode {
+	return &ErrorCode{Status: ERR_PARAM.Status, Code: ERR_PARAM.Code, Msg: fmt.Sprintf(format, a...)}
+}
+
+func (m *tableMeta) column(query string) *columnMeta {
+	for i := range m.columns {
+		if m.columns[i].query == query {
+			return &m.columns[i]
+		}
+	}
+	return nil
+}
+
+func (m *tableMeta) sortColumn(field string) (*columnMeta, error) {
+	c := m.column(field)
+	if c == nil || !c.sortable {
+		return nil, queryParamError("不支持按 %s 排序", field)
+	}
+	return c, nil
+}
+
+// filters 把过滤条件编译为参数化的 SQL 条件，占位符从 $(len(args)+1) 开始。
+func (m *tableMeta) filters(filters []Filter, args []any) ([]string, []any, error) {
+	var conds []string
+	for _, f := range filters {
+		c := m.column(f.Field)
+		if c == nil || !slices.Contains(c.filterOps, f.Op) {
+			return nil, nil, queryParamError("不支持按 %s 的 %s 过滤", f.Field, f.Op)
+		}
+		col := pgx.Identifier{c.name}.Sanitize()
+		switch f.Op {
+		case FilterNull:
+			isNull, err := strconv.ParseBool(f.Value)
+			if err != nil {
+				return nil, nil, queryParamError("过滤条件 %s[null] 的值应为 true / false", f.Field)
+			}
+			if isNull {
+				conds = append(conds, col+" IS NULL")
+			} else {
+				conds = append(conds, col+" IS NOT NULL")
+			}
+			continue
+		case FilterIn, FilterNin:
+			parts := strings.Split(f.Value, ",")
+			list := reflect.MakeSlice(reflect.SliceOf(valueType(c.typ)), len(parts), len(parts))
+			for i, p := range parts {
+				if err := setString(list.Index(i), strings.TrimSpace(p)); err != nil {
+					return nil, nil, queryParamError("过滤条件 %s[%s] 的值 %q 无效", f.Field, f.Op, p)
+				}
+			}
+			args = append(args, list.Interface())
+			conds = append(conds, col+" "+filterOperators[f.Op]+"($"+strconv.Itoa(len(args))+")")
+			continue
+		}
+		v := reflect.New(valueType(c.typ)).Elem()
+		if f.Op == FilterLike {
+			v = reflect.New(reflect.TypeFor[string]()).Elem()
+		}
+		if err := setString(v, f.Value); err != nil {
+			return nil, nil, queryParamError("过滤条件 %s[%s] 的值 %q 无效", f.Field, f.Op, f.Value)
+		}
+		args = append(args, v.Interface())
+		conds = append(conds, col+" "+filterOperators[f.Op]+" $"+strconv.Itoa(len(args)))
+	}
+	return conds, args, nil
+}
+
+// valueType 指针字段（可为 NULL 的列）按其指向的类型解析过滤值。
+func valueType(t reflect.Type) reflect.Type {
+	if t.Kind() == reflect.Pointer {
+		return t.Elem()
+	}
+	return t
+}
+
+// keepFilterable 清零 t 中既不是主键、filter tag 也不含 eq 的列。Handler 从查询参数绑定 T 时 gin 会按字段名匹配，
+// 不清零的话 ?PasswordHash=... 这类请求就能把未放开的列当作等值条件逐个试探；T 无法解析表结构时整体清零。
+func keepFilterable[T any](t *T) {
+	v := reflect.ValueOf(t).Elem()
+	meta, err := metaOf[T]()
+	if err != nil {
+		v.SetZero()
+		return
+	}
+	for _, c := range meta.columns {
+		if !c.pk && !slices.Contains(c.filterOps, FilterEq) {
+			v.FieldByIndex(c.index).SetZero()
+		}
+	}
+}
@@ -0,0 +1,127 @@
+package gowk
+
+import (
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+type testMember struct {
+	ID        int64      `db:"id,pk" json:"id" sort:"true"`
+	Name      string     `db:"name" json:"name" filter:"eq,like"`
+	Age       int        `db:"age" json:"age" filter:"eq,gte,lte,in" sort:"true"`
+	DeletedAt *time.Time `db:"deleted_at" json:"deletedAt" filter:"null"`
+	CreatedAt time.Time  `db:"created_at" json:"createdAt" sort:"true"`
+	Remark    string     `db:"remark"`
+}
+
+func TestParseQuerySpec(t *testing.T) {
+	values, _ := url.ParseQuery("filter[age][gte]=18&filter[age][in]=18,20&filter[name]=tom&filter[deletedAt][null]=true&sort=-createdAt,age&size=10")
+	spec, err := ParseQuerySpec[testMember](values)
+	if err != nil {
+		t.Fatal(err)
+	}
+	want := QuerySpec{
+		Filters: []Filter{
+			{Field: "age", Op: FilterGte, Value: "18"},
+			{Field: "age", Op: FilterIn, Value: "18,20"},
+			{Field: "deletedAt", Op: FilterNull, Value: "true"},
+			{Field: "name", Op: FilterEq, Value: "tom"},
+		},
+		Sorts: []Sort{{Field: "createdAt", Desc: true}, {Field: "age"}},
+	}
+	if !reflect.DeepEqual(spec, want) {
+		t.Fatalf("spec = %+v", spec)
+	}
+
+	meta, _ := metaOf[testMember]()
+	where, args, err := meta.where(&testMember{Name: "x"}, spec)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if where != ` WHERE "name" = $1 AND "age" >= $2 AND "age" = ANY($3) AND "deleted_at" IS NULL AND "name" = $4` {
+		t.Fatalf("where = %s", where)
+	}
+	if !reflect.DeepEqual(args, []any{"x", 18, []int{18, 20}, "tom"}) {
+		t.Fatalf("args = %#v", args)
+	}
+	order, err := meta.orderBy(spec.Sorts)
+	if err != nil || order != ` ORDER BY "created_at" DESC, "age", "id"` {
+		t.Fatalf("orderBy = %q %v", order, err)
+	}
+
+	for _, query := range []string{
+		"filter[remark]=a",
+		"filter[age][like]=1",
+		"filter[age][gte]=abc",
+		"filter[age][in]=1,x",
+		"filter[deletedAt][null]=maybe",
+		"filter[name][regex]=a",
+		"sort=name",
+		"sort=-remark",
+	} {
+		values, _ := url.ParseQuery(query)
+		_, err := ParseQuerySpec[testMember](values)
+		var code *ErrorCode
+		if !errors.As(err, &code) || code.Code != ERR_PARAM.Code {
+			t.Errorf("%s: err = %v", query, err)
+		}
+	}
+}
+
+func TestPageNormalize(t *testing.T) {
+	keepConfig(t)
+	updateConfig(func(c *Config) { c.Page = PageConfig{DefaultSize: 20, MaxSize: 50} })
+	for _, tc := range []struct{ size, current, wantSize, wantCurrent int64 }{
+		{0, 0, 20, 1},
+		{10, 3, 10, 3},
+		{1000, -1, 50, 1},
+	} {
+		p := PageModel[testMember]{Size: tc.size, Current: tc.current}
+		p.Normalize()
+		if p.Size != tc.wantSize || p.Current != tc.wantCurrent {
+			t.Errorf("Normalize(%d, %d) = %d, %d", tc.size, tc.current, p.Size, p.Current)
+		}
+	}
+}
+
+// recordMembers 只记录 Handler 传入的查询条件。
+type recordMembers struct {
+	q *testMember
+}
+
+func (r *recordMembers) Page(page *PageModel[testMember], q *testMember) (*PageModel[testMember], error) {
+	*r.q = *q
+	return page, nil
+}
+
+func (r *recordMembers) One(q *testMember) (testMember, error) {
+	*r.q = *q
+	return *q, nil
+}
+
+func (r *recordMembers) Update(*testMember) error { return nil }
+func (r *recordMembers) Save(*testMember) error   { return nil }
+func (r *recordMembers) Delete(*testMember) error { return nil }
+
+func TestHandlerQueryParamWhitelist(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	var q testMember
+	Resource[testMember](engine.Group(""), "/members", sharedService[testMember](&recordMembers{q: &q}))
+
+	for _, path := range []string{"/members?Name=tom&Age=18&Remark=x", "/members/5?Name=tom&Age=18&Remark=x"} {
+		q = testMember{}
+		engine.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, path, nil))
+		// Name / Age 的 filter tag 含 eq，Remark 没有 filter tag，绑定后应被清零。
+		if q.Name != "tom" || q.Age != 18 || q.Remark != "" {
+			t.Errorf("%s: query param = %+v", path, q)
+		}
+	}
+}
@@ -33,29 +33,34 @@ func initRedis() {
 	// NewClient 只建一次：go-redis 内部维护连接池与后台心跳，反复 New 会积累资源。
 	// 这里成功前不 Store 到 defaultRedis，避免外部在未 Ping 通时就拿到一个不可用 client。
 	client := redis.NewClient(&redis.Options{
-		Addr:     redisAddr,
-		Password: redisPassword,
-		DB:       redisDB,
+		Addr:     conf().Redis.Addr,
+		Password: conf().Redis.Password,
+		DB:       conf().Redis.DB,
 	})
 
 	ctx, cancel := context.WithCancel(context.Background())
 	redisRetryCancel = cancel
+	redisHealth.set(HealthConnecting, nil)
 	go func() {
-		retryBackground(ctx, "Redis", redisRetryBaseInterval, redisRetryMaxInterval, func(c context.Context) error {
-			pingCtx, cancelPing := context.WithTimeout(c, redisPingTimeout)
+		retryBackground(ctx, "Redis", conf().Redis.RetryBaseInterval, conf().Redis.RetryMaxInterval, redisHealth.observe, func(c context.Context) error {
+			pingCtx, cancelPing := context.WithTimeout(c, conf().Redis.PingTimeout)
 			defer cancelPing()
 			if err := client.Ping(pingCtx).Err(); err != nil {
 				return err
 			}
 			defaultRedis.Store(client)
-			slog.Info("Redis 就绪", "addr", redisAddr)
+			slog.Info("Redis 就绪", "addr", conf().Redis.Addr)
 			return nil
 		})
 		// ctx 取消路径下如果始终没 Ping 通，defaultRedis 仍为 nil，
 		// 这里收尾关闭掉未发布出去的 client，避免 goroutine/连接泄漏。
 		if defaultRedis.Load() == nil {
 			_ = client.Close()
+			return
 		}
+		monitorBackground(ctx, redisHealth, conf().Health.Interval, conf().Redis.PingTimeout, func(c context.Context) error {
+			return client.Ping(c).Err()
+		})
 	}()
 }
 
@@ -0,0 +1,172 @@
+package gowk
+
+import (
+	"context"
+	"fmt"
+	"log/slog"
+	"os"
+	"reflect"
+	"slices"
+	"sync"
+	"time"
+)
+
+// configSource 最近一次 LoadConfig / ReloadConfig 生效的配置（结构体指针）与文件，ReloadConfig 按它重新加载。
+// cfg 为 nil 表示还没调用过 LoadConfig，此时只有按环境变量加载的 gowk 配置。
+// base 为 LoadConfig 绑定前传入结构体的拷贝，即代码里预先赋的值，每次重载从它开始绑定。
+var configSource struct {
+	mu      sync.Mutex
+	cfg     any
+	base    any
+	files   []string
+	origins map[string]configOrigin
+}
+
+var configSubscribers struct {
+	mu  sync.Mutex
+	fns []func(old, new any)
+}
+
+// OnConfigChange 注册配置变更回调，LoadConfig 与每次 ReloadConfig 成功后按注册顺序同步调用。
+// old / new 为 LoadConfig 传入的结构体类型 T 的指针；T 为 Config 时拿到的是其中的 gowk 配置。
+// 首次 LoadConfig 之前没有业务配置，T 不是 Config 的回调从第一次重载开始才会被调用。
+// 回调里不要修改 old / new，panic 会被恢复并记日志，不影响其他回调。
+func OnConfigChange[T any](fn func(old, new *T)) {
+	configSubscribers.mu.Lock()
+	defer configSubscribers.mu.Unlock()
+	configSubscribers.fns = append(configSubscribers.fns, func(old, new any) {
+		o, n := configAs[T](old), configAs[T](new)
+		if o != nil && n != nil {
+			fn(o, n)
+		}
+	})
+}
+
+func configAs[T any](v any) *T {
+	if t, ok := v.(*T); ok {
+		return t
+	}
+	if c := findConfig(reflect.ValueOf(v)); c != nil {
+		if t, ok := any(c).(*T); ok {
+			return t
+		}
+	}
+	return nil
+}
+
+// ReloadConfig 按最近一次 LoadConfig 的类型与文件重新加载配置（未调用过 LoadConfig 时只重读环境变量），
+// 校验通过后生效并通知 OnConfigChange 订阅者；失败时保持原配置并返回 error。
+// 重载不会修改 LoadConfig 传入的结构体，新值通过订阅回调获取；与 LoadConfig 一样，代码里在 LoadConfig 之前
+// 预先赋的值优先于 default tag。
+func ReloadConfig() error {
+	configSource.mu.Lock()
+	defer configSource.mu.Unlock()
+	old := configSource.cfg
+	if old == nil {
+		old = conf()
+	}
+	fresh := reflect.New(reflect.TypeOf(old).Elem())
+	if configSource.base != nil {
+		fresh = cloneConfig(reflect.ValueOf(configSource.base))
+	}
+	origins, err := bindConfig(fresh.Interface(), configSource.files)
+	if err != nil {
+		return err
+	}
+	configSource.cfg, configSource.origins = fresh.Interface(), origins
+	publishConfig(old, fresh.Interface())
+	slog.Info("配置已重载", "files", configSource.files)
+	return nil
+}
+
+// publishConfig 让 new 中的 gowk 配置生效并通知订阅者，调用方持有 configSource.mu。
+func publishConfig(old, new any) {
+	if old == nil {
+		old = conf()
+	}
+	if c := findConfig(reflect.ValueOf(new)); c != nil {
+		configMu.Lock()
+		cp := *c
+		for _, fn := range configOverrides {
+			fn(&cp)
+		}
+		currentConfig.Store(&cp)
+		confErr = nil
+		configMu.Unlock()
+	}
+	configSubscribers.mu.Lock()
+	fns := slices.Clone(configSubscribers.fns)
+	configSubscribers.mu.Unlock()
+	for _, fn := range fns {
+		notifyConfigChange(fn, old, new)
+	}
+}
+
+func notifyConfigChange(fn func(old, new any), old, new any) {
+	defer func() {
+		if r := recover(); r != nil {
+			slog.Error("配置变更回调 panic", "panic", fmt.Sprint(r))
+		}
+	}()
+	fn(old, new)
+}
+
+// gowk 自身可在运行期调整的设置：日志级别、token 有效期、API key 名。只在值变化时覆盖，
+// 代码里通过 SetLogLevel / SetTokenTimeout / SetClientKeyNames 设置的值在配置未改动这些项时保持不变。
+func init() {
+	OnConfigChange(func(old, new *Config) {
+		if old.LogLevel != new.LogLevel {
+			SetLogLevel(new.LogLevel)
+			slog.Info("日志级别已更新", "from", old.LogLevel.String(), "to", new.LogLevel.String())
+		}
+		if old.Auth.TokenTimeout != new.Auth.TokenTimeout {
+			SetTokenTimeout(int64(new.Auth.TokenTimeout / time.Second))
+			slog.Info("token 有效期已更新", "from", old.Auth.TokenTimeout.String(), "to", new.Auth.TokenTimeout.String())
+		}
+		if !slices.Equal(old.Auth.ClientKeyNames, new.Auth.ClientKeyNames) {
+			SetClientKeyNames(new.Auth.ClientKeyNames...)
+			slog.Info("API key 名已更新", "from", old.Auth.ClientKeyNames, "to", new.Auth.ClientKeyNames)
+		}
+	})
+}
+
+// watchConfigFiles 按 CONFIG_WATCH_INTERVAL 检查配置文件的修改时间与大小，变化后 ReloadConfig。
+// 未配置间隔或没有配置文件时直接返回。
+func watchConfigFiles(ctx context.Context) {
+	interval := conf().Lifecycle.ConfigWatchInterval
+	configSource.mu.Lock()
+	files := slices.Clone(configSource.files)
+	configSource.mu.Unlock()
+	if interval <= 0 || len(files) == 0 {
+		return
+	}
+	last := configFileStamps(files)
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+		stamps := configFileStamps(files)
+		if slices.Equal(stamps, last) {
+			continue
+		}
+		last = stamps
+		if err := ReloadConfig(); err != nil {
+			slog.Error("配置文件已变化，但重载失败，保持原配置", "err", err)
+		}
+	}
+}
+
+// configFileStamps 返回各文件的修改时间与大小，读不到的文件记为空。
+func configFileStamps(files []string) []string {
+	stamps := make([]string, len(files))
+	for i, f := range files {
+		if fi, err := os.Stat(f); err == nil {
+			stamps[i] = fmt.Sprintf("%d/%d", fi.ModTime().UnixNano(), fi.Size())
+		}
+	}
+	return stamps
+}
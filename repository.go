@@ -0,0 +1,599 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"unicode"
+
+	"github.com/jackc/pgx/v5"
+	"github.com/jackc/pgx/v5/pgconn"
+)
+
+// Repository 基于 struct tag 的 Postgres 通用 CRUD，Service[T] 的默认实现。
+//
+//	type User struct {
+//		_         struct{}  `table:"users"`
+//		ID        int64     `db:"id,pk,omitinsert" json:"id"`
+//		Name      string    `db:"name" json:"name"`
+//		CreatedAt time.Time `db:"created_at,omitinsert" json:"createdAt"`
+//	}
+//
+// tag 约定：
+//   - 表名：空白字段 _ struct{} 的 table tag，或 T 实现 TableName() string，都没有时为类型名的 snake_case，可带 schema（如 app.users）；
+//   - 列名：db tag 逗号前的部分（与 pgx.RowToStructByName 一致，带选项时不能省略），没有 db tag 时为字段名的 snake_case，db:"-" 不映射；
+//   - db tag 选项 pk 为主键（仅支持单列），omitinsert 插入时不写入该列（自增 id、数据库默认值）；
+//   - 匿名嵌入的结构体字段展开为同一张表的列。
+//
+// 开启了事务（Begin）时通过 PostgresNamedTx 执行，否则使用连接池；写操作总是在主库上执行。
+type Repository[T any] struct {
+	// DB 命名实例名，为空时使用默认实例。
+	DB string
+}
+
+func NewRepository[T any]() *Repository[T] {
+	return &Repository[T]{}
+}
+
+type columnMeta struct {
+	name       string
+	index      []int
+	typ        reflect.Type
+	pk         bool
+	omitInsert bool
+	// query 过滤与排序参数中使用的字段名：json tag 名，没有时为列名。
+	query string
+	// filterOps filter tag 允许的过滤操作，sortable 为 sort:"true"，见 QuerySpec。
+	filterOps []FilterOp
+	sortable  bool
+}
+
+// tableMeta 由 T 的 struct tag 解析出的表结构，按类型缓存。
+type tableMeta struct {
+	table   string
+	columns []columnMeta
+	pk      *columnMeta
+	// selectList 转义后的全部列，用于 SELECT / RETURNING。
+	selectList string
+}
+
+var tableMetas sync.Map // reflect.Type -> *tableMeta / error
+
+type tableNamer interface{ TableName() string }
+
+func metaOf[T any]() (*tableMeta, error) {
+	t := reflect.TypeFor[T]()
+	if v, ok := tableMetas.Load(t); ok {
+		if err, ok := v.(error); ok {
+			return nil, err
+		}
+		return v.(*tableMeta), nil
+	}
+	meta, err := parseTableMeta(t)
+	if err != nil {
+		tableMetas.Store(t, err)
+		return nil, err
+	}
+	tableMetas.Store(t, meta)
+	return meta, nil
+}
+
+func parseTableMeta(t reflect.Type) (*tableMeta, error) {
+	if t.Kind() != reflect.Struct {
+		return nil, fmt.Errorf("Repository 需要结构体类型，实际为 %s", t)
+	}
+	meta := &tableMeta{table: toSnakeCase(t.Name())}
+	if n, ok := reflect.New(t).Interface().(tableNamer); ok {
+		meta.table = n.TableName()
+	}
+	if err := meta.addColumns(t, nil); err != nil {
+		return nil, err
+	}
+	if meta.table == "" {
+		return nil, fmt.Errorf("%s 没有表名", t)
+	}
+	if len(meta.columns) == 0 {
+		return nil, fmt.Errorf("%s 没有可映射的列", t)
+	}
+	names := make([]string, len(meta.columns))
+	for i := range meta.columns {
+		c := &meta.columns[i]
+		if c.pk {
+			if meta.pk != nil {
+				return nil, fmt.Errorf("%s 只支持单列主键，%s 与 %s 都标记了 pk", t, meta.pk.name, c.name)
+			}
+			meta.pk = c
+		}
+		names[i] = pgx.Identifier{c.name}.Sanitize()
+	}
+	meta.selectList = strings.Join(names, ", ")
+	return meta, nil
+}
+
+func (m *tableMeta) addColumns(t reflect.Type, index []int) error {
+	for i := range t.NumField() {
+		f := t.Field(i)
+		if f.Name == "_" {
+			if name := f.Tag.Get("table"); name != "" {
+				m.table = name
+			}
+			continue
+		}
+		idx := append(append([]int(nil), index...), i)
+		if f.Anonymous && f.Type.Kind() == reflect.Struct {
+			if err := m.addColumns(f.Type, idx); err != nil {
+				return err
+			}
+			continue
+		}
+		tag, hasTag := f.Tag.Lookup("db")
+		if !f.IsExported() || tag == "-" {
+			continue
+		}
+		name, opts, _ := strings.Cut(tag, ",")
+		if name == "" {
+			// pgx 扫描时 db tag 存在就按 tag 中的列名匹配，这里不能替它补默认值。
+			if hasTag {
+				return fmt.Errorf("%s.%s 的 db tag 缺少列名", t, f.Name)
+			}
+			name = toSnakeCase(f.Name)
+		}
+		c := columnMeta{name: name, index: idx, typ: f.Type, query: name, sortable: f.Tag.Get("sort") == "true"}
+		if jsonName, _, _ := strings.Cut(f.Tag.Get("json"), ","); jsonName != "" && jsonName != "-" {
+			c.query = jsonName
+		}
+		if ops := f.Tag.Get("filter"); ops != "" {
+			for _, op := range strings.Split(ops, ",") {
+				op := FilterOp(strings.TrimSpace(op))
+				if _, ok := filterOperators[op]; !ok {
+					return fmt.Errorf("%s.%s 的 filter tag 操作 %q 无效", t, f.Name, op)
+				}
+				c.filterOps = append(c.filterOps, op)
+			}
+		}
+		for _, opt := range strings.Split(opts, ",") {
+			switch strings.TrimSpace(opt) {
+			case "pk":
+				c.pk = true
+			case "omitinsert":
+				c.omitInsert = true
+			case "":
+			default:
+				return fmt.Errorf("%s.%s 的 db tag 选项 %q 无效", t, f.Name, opt)
+			}
+		}
+		m.columns = append(m.columns, c)
+	}
+	return nil
+}
+
+func (m *tableMeta) tableIdent() string {
+	return pgx.Identifier(strings.Split(m.table, ".")).Sanitize()
+}
+
+// equals 以 v 中非零值的字段作为等值条件，占位符从 $(len(args)+1) 开始。
+func (m *tableMeta) equals(v reflect.Value, args []any) ([]string, []any) {
+	var conds []string
+	for _, c := range m.columns {
+		f := v.FieldByIndex(c.index)
+		if f.IsZero() {
+			continue
+		}
+		args = append(args, f.Interface())
+		conds = append(conds, pgx.Identifier{c.name}.Sanitize()+" = $"+strconv.Itoa(len(args)))
+	}
+	return conds, args
+}
+
+// where 以 queryParam 中非零值的字段与 spec 的过滤条件生成 WHERE 子句（AND 连接）。
+func (m *tableMeta) where(queryParam any, spec QuerySpec) (string, []any, error) {
+	var conds []string
+	var args []any
+	if v := reflect.ValueOf(queryParam); v.IsValid() && !v.IsNil() {
+		conds, args = m.equals(v.Elem(), args)
+	}
+	filterConds, args, err := m.filters(spec.Filters, args)
+	if err != nil {
+		return "", nil, err
+	}
+	conds = append(conds, filterConds...)
+	if len(conds) == 0 {
+		return "", args, nil
+	}
+	return " WHERE " + strings.Join(conds, " AND "), args, nil
+}
+
+// sortKey 排序字段及方向。
+type sortKey struct {
+	col  *columnMeta
+	desc bool
+}
+
+// sortKeys 先按 sorts 排序，最后按主键排序，保证分页稳定。
+func (m *tableMeta) sortKeys(sorts []Sort) ([]sortKey, error) {
+	var keys []sortKey
+	pkSorted := false
+	for _, s := range sorts {
+		c, err := m.sortColumn(s.Field)
+		if err != nil {
+			return nil, err
+		}
+		keys = append(keys, sortKey{col: c, desc: s.Desc})
+		pkSorted = pkSorted || c.pk
+	}
+	if m.pk != nil && !pkSorted {
+		keys = append(keys, sortKey{col: m.pk})
+	}
+	return keys, nil
+}
+
+// orderBy 见 sortKeys。
+func (m *tableMeta) orderBy(sorts []Sort) (string, error) {
+	keys, err := m.sortKeys(sorts)
+	if err != nil {
+		return "", err
+	}
+	return orderByKeys(keys, false), nil
+}
+
+// orderByKeys reverse 为 true 时各字段反向排序（游标分页向前翻页）。
+func orderByKeys(keys []sortKey, reverse bool) string {
+	if len(keys) == 0 {
+		return ""
+	}
+	terms := make([]string, len(keys))
+	for i, k := range keys {
+		terms[i] = pgx.Identifier{k.col.name}.Sanitize()
+		if k.desc != reverse {
+			terms[i] += " DESC"
+		}
+	}
+	return " ORDER BY " + strings.Join(terms, ", ")
+}
+
+// pgQuerier pgxpool.Pool 与 pgx.Tx 的公共部分。
+type pgQuerier interface {
+	Exec(ctx context.Context, sql string, args ...any) (pgconn.CommandTag, error)
+	Query(ctx context.Context, sql string, args ...any) (pgx.Rows, error)
+	QueryRow(ctx context.Context, sql string, args ...any) pgx.Row
+}
+
+// querier 已 Begin 时返回事务，否则返回连接池；write 为 true 时不走只读副本。
+func (r *Repository[T]) querier(ctx context.Context, write bool) (pgQuerier, error) {
+	if tx, err := PostgresNamedTx(ctx, r.DB); err == nil {
+		return tx, nil
+	} else if inTransaction(ctx) {
+		return nil, err
+	}
+	if write || r.DB != "" {
+		if p := postgresInstance(r.DB); p != nil {
+			if pool := p.get(); pool != nil {
+				return pool, nil
+			}
+		}
+	} else if pool := Postgres(ctx); pool != nil {
+		return pool, nil
+	}
+	if r.DB != "" {
+		return nil, fmt.Errorf("postgres %s unavailable", r.DB)
+	}
+	return nil, errors.New("postgres unavailable")
+}
+
+// Page 以 queryParam 中非零值的字段为等值条件、pageModel.Query 为过滤与排序条件分页查询：
+// 先 COUNT 再按排序字段与主键排序 LIMIT / OFFSET；Size 按 PAGE_DEFAULT_SIZE / PAGE_MAX_SIZE 修正，Current <= 0 时为 1。
+func (r *Repository[T]) Page(ctx context.Context, pageModel *PageModel[T], queryParam *T) (*PageModel[T], error) {
+	meta, err := metaOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	q, err := r.querier(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	pageModel.Normalize()
+	where, args, err := meta.where(queryParam, pageModel.Query)
+	if err != nil {
+		return nil, err
+	}
+	orderBy, err := meta.orderBy(pageModel.Query.Sorts)
+	if err != nil {
+		return nil, err
+	}
+	if err := q.QueryRow(ctx, "SELECT count(*) FROM "+meta.tableIdent()+where, args...).Scan(&pageModel.Total); err != nil {
+		return nil, err
+	}
+	pageModel.CalcPages()
+	pageModel.Records = []*T{}
+	if pageModel.Total == 0 || pageModel.Current > pageModel.Pages {
+		return pageModel, nil
+	}
+	args = append(args, pageModel.Size, (pageModel.Current-1)*pageModel.Size)
+	sql := "SELECT " + meta.selectList + " FROM " + meta.tableIdent() + where + orderBy +
+		" LIMIT $" + strconv.Itoa(len(args)-1) + " OFFSET $" + strconv.Itoa(len(args))
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[T])
+	if err != nil {
+		return nil, err
+	}
+	pageModel.Records = append(pageModel.Records, records...)
+	return pageModel, nil
+}
+
+// CursorPage 游标分页：条件同 Page，按排序字段与主键定位 page.Cursor 的边界，取其后（或之前）的 Size 条；
+// 多查一条判断是否还有下一页，并生成 NextCursor / PrevCursor。排序字段必须非空（不能是指针字段），
+// 游标与当前排序方式不匹配或签名无效时返回 ERR_PARAM 类错误。WithTotal 为 true 时额外 COUNT。
+func (r *Repository[T]) CursorPage(ctx context.Context, page *CursorPage[T], queryParam *T) (*CursorPage[T], error) {
+	meta, err := metaOf[T]()
+	if err != nil {
+		return nil, err
+	}
+	if meta.pk == nil {
+		return nil, fmt.Errorf("%s 没有标记 pk 的列，无法游标分页", meta.table)
+	}
+	keys, err := meta.sortKeys(page.Query.Sorts)
+	if err != nil {
+		return nil, err
+	}
+	for _, k := range keys {
+		if k.col.typ.Kind() == reflect.Pointer {
+			return nil, queryParamError("游标分页不支持按可为空的 %s 排序", k.col.query)
+		}
+	}
+	page.Normalize()
+	where, args, err := meta.where(queryParam, page.Query)
+	if err != nil {
+		return nil, err
+	}
+	var prev bool
+	var boundary []any
+	if page.Cursor != "" {
+		if prev, boundary, err = decodeCursor(page.Cursor, meta.table, keys); err != nil {
+			return nil, err
+		}
+	}
+	q, err := r.querier(ctx, false)
+	if err != nil {
+		return nil, err
+	}
+	if page.WithTotal {
+		var total int64
+		if err := q.QueryRow(ctx, "SELECT count(*) FROM "+meta.tableIdent()+where, args...).Scan(&total); err != nil {
+			return nil, err
+		}
+		page.Total = &total
+	}
+	if boundary != nil {
+		var cond string
+		cond, args = keysetCondition(keys, boundary, prev, args)
+		if where == "" {
+			where = " WHERE " + cond
+		} else {
+			where += " AND " + cond
+		}
+	}
+	args = append(args, page.Size+1)
+	sql := "SELECT " + meta.selectList + " FROM " + meta.tableIdent() + where + orderByKeys(keys, prev) +
+		" LIMIT $" + strconv.Itoa(len(args))
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return nil, err
+	}
+	records, err := pgx.CollectRows(rows, pgx.RowToAddrOfStructByName[T])
+	if err != nil {
+		return nil, err
+	}
+	more := int64(len(records)) > page.Size
+	if more {
+		records = records[:page.Size]
+	}
+	if prev {
+		slices.Reverse(records)
+	}
+	page.Records = append([]*T{}, records...)
+	page.NextCursor, page.PrevCursor = "", ""
+	if len(records) == 0 {
+		return page, nil
+	}
+	// 向后翻页时还有下一页取决于 more，能翻回去取决于是否带了游标；向前翻页时相反。
+	hasNext, hasPrev := more, boundary != nil
+	if prev {
+		hasNext, hasPrev = true, more
+	}
+	if hasNext {
+		if page.NextCursor, err = encodeCursor(meta.table, keys, reflect.ValueOf(records[len(records)-1]).Elem(), false); err != nil {
+			return nil, err
+		}
+	}
+	if hasPrev {
+		if page.PrevCursor, err = encodeCursor(meta.table, keys, reflect.ValueOf(records[0]).Elem(), true); err != nil {
+			return nil, err
+		}
+	}
+	return page, nil
+}
+
+// One 以 queryParam 中非零值的字段为等值条件查询一条（按主键排序取第一条），没有时返回 ERR_NODATA。
+func (r *Repository[T]) One(ctx context.Context, queryParam *T) (T, error) {
+	var model T
+	meta, err := metaOf[T]()
+	if err != nil {
+		return model, err
+	}
+	q, err := r.querier(ctx, false)
+	if err != nil {
+		return model, err
+	}
+	where, args, err := meta.where(queryParam, QuerySpec{})
+	if err != nil {
+		return model, err
+	}
+	orderBy, _ := meta.orderBy(nil)
+	rows, err := q.Query(ctx, "SELECT "+meta.selectList+" FROM "+meta.tableIdent()+where+orderBy+" LIMIT 1", args...)
+	if err != nil {
+		return model, err
+	}
+	model, err = pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[T])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return model, ERR_NODATA
+	}
+	return model, err
+}
+
+// Save 插入一条记录（跳过 omitinsert 的列），并把 RETURNING 的整行（自增 id、默认值）写回 postParam。
+func (r *Repository[T]) Save(ctx context.Context, postParam *T) error {
+	meta, err := metaOf[T]()
+	if err != nil {
+		return err
+	}
+	q, err := r.querier(ctx, true)
+	if err != nil {
+		return err
+	}
+	v := reflect.ValueOf(postParam).Elem()
+	var cols, placeholders []string
+	var args []any
+	for _, c := range meta.columns {
+		if c.omitInsert {
+			continue
+		}
+		args = append(args, v.FieldByIndex(c.index).Interface())
+		cols = append(cols, pgx.Identifier{c.name}.Sanitize())
+		placeholders = append(placeholders, "$"+strconv.Itoa(len(args)))
+	}
+	sql := "INSERT INTO " + meta.tableIdent()
+	if len(cols) == 0 {
+		sql += " DEFAULT VALUES"
+	} else {
+		sql += " (" + strings.Join(cols, ", ") + ") VALUES (" + strings.Join(placeholders, ", ") + ")"
+	}
+	return r.returning(ctx, q, sql+" RETURNING "+meta.selectList, args, postParam)
+}
+
+// Update 按主键更新 postParam 中除主键与 omitinsert 以外的所有列（零值也会写入），并把更新后的整行写回 postParam；
+// 主键为零值时返回 ERR_PARAM，记录不存在时返回 ERR_NODATA。只更新部分字段用 Patch。
+func (r *Repository[T]) Update(ctx context.Context, postParam *T) error {
+	return r.update(ctx, postParam, false)
+}
+
+// Patch 同 Update，但只更新 postParam 中非零值的列：零值（false、0、""）无法通过 Patch 写入，需要时用 Update。
+// 没有非零值的列时返回 ERR_PARAM。
+func (r *Repository[T]) Patch(ctx context.Context, postParam *T) error {
+	return r.update(ctx, postParam, true)
+}
+
+func (r *Repository[T]) update(ctx context.Context, postParam *T, skipZero bool) error {
+	meta, err := metaOf[T]()
+	if err != nil {
+		return err
+	}
+	sql, args, err := meta.updateSQL(reflect.ValueOf(postParam).Elem(), skipZero)
+	if err != nil {
+		return err
+	}
+	q, err := r.querier(ctx, true)
+	if err != nil {
+		return err
+	}
+	return r.returning(ctx, q, sql, args, postParam)
+}
+
+// updateSQL 生成按主键更新 v 的语句：skipZero 时只写非零值的列（Patch），否则写除 omitinsert 以外的所有列（Update）。
+func (m *tableMeta) updateSQL(v reflect.Value, skipZero bool) (string, []any, error) {
+	if m.pk == nil {
+		return "", nil, fmt.Errorf("%s 没有标记 pk 的列，无法按主键更新", m.table)
+	}
+	pk := v.FieldByIndex(m.pk.index)
+	if pk.IsZero() {
+		return "", nil, ERR_PARAM
+	}
+	var sets []string
+	var args []any
+	for _, c := range m.columns {
+		f := v.FieldByIndex(c.index)
+		if c.pk || (skipZero && f.IsZero()) || (!skipZero && c.omitInsert) {
+			continue
+		}
+		args = append(args, f.Interface())
+		sets = append(sets, pgx.Identifier{c.name}.Sanitize()+" = $"+strconv.Itoa(len(args)))
+	}
+	if len(sets) == 0 {
+		return "", nil, ERR_PARAM
+	}
+	args = append(args, pk.Interface())
+	sql := "UPDATE " + m.tableIdent() + " SET " + strings.Join(sets, ", ") +
+		" WHERE " + pgx.Identifier{m.pk.name}.Sanitize() + " = $" + strconv.Itoa(len(args)) +
+		" RETURNING " + m.selectList
+	return sql, args, nil
+}
+
+// Delete 按主键删除 postParam 对应的记录，主键为零值时返回 ERR_PARAM，记录不存在时返回 ERR_NODATA。
+func (r *Repository[T]) Delete(ctx context.Context, postParam *T) error {
+	meta, err := metaOf[T]()
+	if err != nil {
+		return err
+	}
+	if meta.pk == nil {
+		return fmt.Errorf("%s 没有标记 pk 的列，无法按主键删除", meta.table)
+	}
+	pk := reflect.ValueOf(postParam).Elem().FieldByIndex(meta.pk.index)
+	if pk.IsZero() {
+		return ERR_PARAM
+	}
+	q, err := r.querier(ctx, true)
+	if err != nil {
+		return err
+	}
+	tag, err := q.Exec(ctx, "DELETE FROM "+meta.tableIdent()+" WHERE "+pgx.Identifier{meta.pk.name}.Sanitize()+" = $1", pk.Interface())
+	if err != nil {
+		return err
+	}
+	if tag.RowsAffected() == 0 {
+		return ERR_NODATA
+	}
+	return nil
+}
+
+// returning 执行带 RETURNING 的写语句并把结果写回 dst，没有返回行时为 ERR_NODATA。
+func (r *Repository[T]) returning(ctx context.Context, q pgQuerier, sql string, args []any, dst *T) error {
+	rows, err := q.Query(ctx, sql, args...)
+	if err != nil {
+		return err
+	}
+	row, err := pgx.CollectExactlyOneRow(rows, pgx.RowToStructByName[T])
+	if errors.Is(err, pgx.ErrNoRows) {
+		return ERR_NODATA
+	}
+	if err != nil {
+		return err
+	}
+	*dst = row
+	return nil
+}
+
+// toSnakeCase 把 Go 标识符转为 snake_case：UserID → user_id，CreatedAt → created_at。
+func toSnakeCase(s string) string {
+	runes := []rune(s)
+	var b strings.Builder
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			// 小写后接大写（userId）或缩写结束（IDCard 中的 C）处断开。
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(i+1 < len(runes) && unicode.IsLower(runes[i+1]) && unicode.IsUpper(runes[i-1]))) {
+				b.WriteByte('_')
+			}
+			b.WriteRune(unicode.ToLower(r))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
@@ -0,0 +1,91 @@
+package gowk
+
+import (
+	"context"
+	"reflect"
+	"strings"
+	"testing"
+	"time"
+)
+
+type testAudit struct {
+	CreatedAt time.Time `db:"created_at,omitinsert"`
+}
+
+type testUser struct {
+	_      struct{} `table:"app.users"`
+	ID     int64    `db:"id,pk,omitinsert"`
+	Name   string
+	UserID string
+	Note   string `db:"-"`
+	secret string
+	testAudit
+}
+
+func TestTableMeta(t *testing.T) {
+	meta, err := metaOf[testUser]()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if meta.tableIdent() != `"app"."users"` || meta.pk == nil || meta.pk.name != "id" {
+		t.Fatalf("meta = %+v", meta)
+	}
+	if meta.selectList != `"id", "name", "user_id", "created_at"` {
+		t.Fatalf("columns = %s", meta.selectList)
+	}
+	where, args, err := meta.where(&testUser{Name: "a", UserID: "u1"}, QuerySpec{})
+	if err != nil || where != ` WHERE "name" = $1 AND "user_id" = $2` || len(args) != 2 {
+		t.Fatalf("where = %q %v %v", where, args, err)
+	}
+
+	type badOption struct {
+		ID int64 `db:"id,primary"`
+	}
+	type twoPK struct {
+		A int64 `db:"a,pk"`
+		B int64 `db:"b,pk"`
+	}
+	type noName struct {
+		ID int64 `db:",pk"`
+	}
+	for _, typ := range []reflect.Type{reflect.TypeFor[badOption](), reflect.TypeFor[twoPK](), reflect.TypeFor[noName]()} {
+		if _, err := parseTableMeta(typ); err == nil {
+			t.Errorf("%s should be rejected", typ)
+		}
+	}
+
+	for in, want := range map[string]string{"UserID": "user_id", "CreatedAt": "created_at", "ID": "id", "IDCard": "id_card", "Level2FA": "level2_fa"} {
+		if got := toSnakeCase(in); got != want {
+			t.Errorf("toSnakeCase(%s) = %s, want %s", in, got, want)
+		}
+	}
+}
+
+func TestRepositoryUnavailable(t *testing.T) {
+	repo := NewRepository[testUser]()
+	if _, err := repo.Page(context.Background(), &PageModel[testUser]{}, nil); err == nil || !strings.Contains(err.Error(), "unavailable") {
+		t.Fatalf("Page = %v", err)
+	}
+	if err := repo.Update(context.Background(), &testUser{Name: "a"}); err != ERR_PARAM {
+		t.Fatalf("Update without pk = %v", err)
+	}
+}
+
+func TestUpdateSQL(t *testing.T) {
+	meta, _ := metaOf[testUser]()
+	v := reflect.ValueOf(testUser{ID: 3, Name: "a"})
+	// Update 写入零值的 user_id，跳过主键与 omitinsert 的 created_at；Patch 只写非零值。
+	sql, args, err := meta.updateSQL(v, false)
+	if err != nil || sql != `UPDATE "app"."users" SET "name" = $1, "user_id" = $2 WHERE "id" = $3 RETURNING "id", "name", "user_id", "created_at"` ||
+		!reflect.DeepEqual(args, []any{"a", "", int64(3)}) {
+		t.Fatalf("update = %s %v %v", sql, args, err)
+	}
+	sql, args, err = meta.updateSQL(v, true)
+	if err != nil || sql != `UPDATE "app"."users" SET "name" = $1 WHERE "id" = $2 RETURNING "id", "name", "user_id", "created_at"` ||
+		!reflect.DeepEqual(args, []any{"a", int64(3)}) {
+		t.Fatalf("patch = %s %v %v", sql, args, err)
+	}
+	if _, _, err := meta.updateSQL(reflect.ValueOf(testUser{ID: 3}), true); err != ERR_PARAM {
+		t.Fatalf("empty patch = %v", err)
+	}
+}
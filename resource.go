@@ -0,0 +1,205 @@
+package gowk
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+)
+
+// Resource 在 group 下注册 T 的 REST 路由，返回的 Handler 可以继续设置 Hooks：
+//
+//	GET    path          列表（Page，分页方式见 Register）
+//	GET    path/:id      详情（One）
+//	POST   path          新增（Save）
+//	PUT    path/:id      整体更新（Update）
+//	PATCH  path/:id      部分更新（Patch）
+//	DELETE path/:id      删除（Delete）
+//	POST   path/batch    批量新增，请求体为 T 的数组
+//	PUT    path/batch    批量整体更新，请求体为 T 的数组，主键取各元素中的值
+//	PATCH  path/batch    批量部分更新，请求体同上
+//	DELETE path/batch    批量删除，请求体为 {"ids": [...]}
+//
+// :id 写入 T 中 db tag 标记 pk 的字段。factory 每个请求创建一次 Service，规则同 NewHandler：
+// 未传或为 nil 时使用默认的 Service[T]。Service 需要按请求创建，才能拿到请求上的事务，批量操作才是原子的。
+func Resource[T any](group *gin.RouterGroup, path string, factory ...func(ctx *gin.Context) CrudService[T]) *Handler[T] {
+	h := NewHandler(factory...)
+	h.Register(group, path)
+	return h
+}
+
+// ResourceService 同 Resource，但所有请求共用 svc。svc 拿不到请求上的事务，批量操作不在同一个事务中，
+// 只适用于不读写 Postgres 或自行管理事务的 Service；使用默认的 Service[T] 时请用 Resource。
+func ResourceService[T any](group *gin.RouterGroup, path string, svc CrudService[T]) *Handler[T] {
+	return Resource(group, path, func(*gin.Context) CrudService[T] { return svc })
+}
+
+// Register 注册 Resource 中列出的路由。Pagination 为空时取配置 page.modes 中该资源的分页方式（见 resourceKey）；
+// T 没有标记 pk 的字段时 panic。游标分页的 Service 见 Handler.CursorService。
+func (h *Handler[T]) Register(group *gin.RouterGroup, path string) {
+	meta, err := metaOf[T]()
+	if err != nil {
+		panic(fmt.Sprintf("Resource[%s]: %v", reflect.TypeFor[T](), err))
+	}
+	if meta.pk == nil {
+		panic(fmt.Sprintf("Resource[%s]: 需要 db tag 标记 pk 的字段", reflect.TypeFor[T]()))
+	}
+	path = strings.TrimSuffix(path, "/")
+	r := group.Group(path)
+	if h.Pagination == "" {
+		h.Pagination = PageMode(conf().Page.Modes[resourceKey(r.BasePath())])
+	}
+	r.GET("", h.Page())
+	r.POST("", h.Save())
+	r.GET("/:id", h.One())
+	r.PUT("/:id", h.Update())
+	r.PATCH("/:id", h.Patch())
+	r.DELETE("/:id", h.Delete())
+	r.POST("/batch", h.BatchSave())
+	r.PUT("/batch", h.BatchUpdate())
+	r.PATCH("/batch", h.BatchPatch())
+	r.DELETE("/batch", h.BatchDelete())
+}
+
+// resourceKey 资源在配置 page.modes 中的键：完整路径去掉首尾 /，/ 和 - 换成 _，小写，如 /api/users 为 api_users。
+func resourceKey(path string) string {
+	return strings.ToLower(strings.NewReplacer("/", "_", "-", "_").Replace(strings.Trim(path, "/")))
+}
+
+// BatchSave 批量新增，请求体为 T 的数组；逐条执行 Save 及其钩子，在同一个事务中完成，任一条失败全部回滚。
+func (h *Handler[T]) BatchSave() gin.HandlerFunc {
+	return h.batch(OpSave, h.Hooks.BeforeSave, h.Hooks.AfterSave, func(svc CrudService[T], t *T) error {
+		return svc.Save(t)
+	})
+}
+
+// BatchUpdate 批量整体更新，请求体为 T 的数组，主键取各元素中的值；事务语义同 BatchSave。
+func (h *Handler[T]) BatchUpdate() gin.HandlerFunc {
+	return h.batch(OpUpdate, h.Hooks.BeforeUpdate, h.Hooks.AfterUpdate, func(svc CrudService[T], t *T) error {
+		return svc.Update(t)
+	})
+}
+
+// BatchPatch 批量部分更新，逐条按 Handler.Patch 的规则执行；请求体与事务语义同 BatchUpdate。
+func (h *Handler[T]) BatchPatch() gin.HandlerFunc {
+	return h.batch(OpUpdate, h.Hooks.BeforeUpdate, h.Hooks.AfterUpdate, patch[T])
+}
+
+// BatchDelete 批量删除，请求体为 {"ids": [...]}；事务语义同 BatchSave。
+func (h *Handler[T]) BatchDelete() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var req struct {
+			IDs []json.RawMessage `json:"ids" binding:"required,min=1"`
+		}
+		if err := ctx.ShouldBindJSON(&req); err != nil {
+			ctx.Error(err)
+			return
+		}
+		items := make([]T, len(req.IDs))
+		for i, raw := range req.IDs {
+			id := string(raw)
+			if strings.HasPrefix(id, `"`) {
+				_ = json.Unmarshal(raw, &id)
+			}
+			if err := setPrimaryKey(&items[i], id); err != nil {
+				ctx.Error(err)
+				return
+			}
+		}
+		h.runBatch(ctx, items, OpDelete, h.Hooks.BeforeDelete, h.Hooks.AfterDelete, func(svc CrudService[T], t *T) error {
+			return svc.Delete(t)
+		}, nil)
+	}
+}
+
+func (h *Handler[T]) batch(op CrudOp, before, after func(*gin.Context, *T) error, fn func(CrudService[T], *T) error) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var items []T
+		if err := ctx.ShouldBindJSON(&items); err != nil {
+			ctx.Error(err)
+			return
+		}
+		if len(items) == 0 {
+			ctx.Error(ERR_PARAM)
+			return
+		}
+		h.runBatch(ctx, items, op, before, after, fn, items)
+	}
+}
+
+// runBatch 在事务中逐条执行，成功后响应 data（为 nil 时只响应成功）。
+func (h *Handler[T]) runBatch(ctx *gin.Context, items []T, op CrudOp, before, after func(*gin.Context, *T) error, fn func(CrudService[T], *T) error, data any) {
+	svc := h.service(ctx)
+	err := withTransaction(ctx, func() error {
+		for i := range items {
+			t := &items[i]
+			if !h.authorize(ctx, op, t) || !h.runHook(ctx, t, before) {
+				return errBatchAborted
+			}
+			if err := fn(svc, t); err != nil {
+				return err
+			}
+			if !h.runHook(ctx, t, after) {
+				return errBatchAborted
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		if !errors.Is(err, errBatchAborted) {
+			ctx.Error(err)
+		}
+		return
+	}
+	Success(ctx, data)
+}
+
+// errBatchAborted 钩子已把错误记入 ctx.Errors，只用于中止批量操作并回滚。
+var errBatchAborted = errors.New("batch aborted")
+
+// withTransaction 让 fn 中通过 PostgresTx / Repository 执行的操作处于同一个事务，返回 fn 或提交失败的 error：
+// 请求已经 Begin 过时加入该事务，由 TransactionHandler 在请求结束时按 ctx.Errors 提交或回滚；
+// 否则在 fn 返回后立即提交或回滚（没有挂 TransactionHandler 时临时创建 Transaction），
+// 保证响应成功时数据已经落库。
+func withTransaction(ctx *gin.Context, fn func() error) error {
+	tx, ok := ctx.Value(TRANSACTION).(*Transaction)
+	if ok && tx != nil {
+		tx.mu.Lock()
+		begun := tx.Begin
+		tx.mu.Unlock()
+		if begun {
+			return fn()
+		}
+	} else {
+		ctx.Set(TRANSACTION, &Transaction{})
+		defer ctx.Set(TRANSACTION, nil)
+	}
+	if err := Begin(ctx); err != nil {
+		return err
+	}
+	defer func() {
+		if r := recover(); r != nil {
+			Rollback(ctx)
+			panic(r)
+		}
+	}()
+	return End(ctx, fn())
+}
+
+// setPrimaryKey 把字符串形式的 id 解析后写入 t 的主键字段（db tag 标记 pk）。
+func setPrimaryKey[T any](t *T, id string) error {
+	meta, err := metaOf[T]()
+	if err != nil {
+		return err
+	}
+	if meta.pk == nil {
+		return fmt.Errorf("%s 没有标记 pk 的列", meta.table)
+	}
+	if err := setString(reflect.ValueOf(t).Elem().FieldByIndex(meta.pk.index), id); err != nil {
+		return &ErrorCode{Status: ERR_PARAM.Status, Code: ERR_PARAM.Code, Msg: "id 无效: " + id}
+	}
+	return nil
+}
@@ -0,0 +1,249 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/jackc/pgx/v5"
+)
+
+type testBook struct {
+	ID    int64  `db:"id,pk,omitinsert" json:"id" form:"id"`
+	Title string `db:"title" json:"title" form:"title"`
+}
+
+// memoryBooks 内存实现的 CrudService，被所有请求共用。
+type memoryBooks struct {
+	mu     sync.Mutex
+	nextID int64
+	books  map[int64]testBook
+}
+
+func (m *memoryBooks) Page(page *PageModel[testBook], q *testBook) (*PageModel[testBook], error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	page.Total = int64(len(m.books))
+	page.CalcPages()
+	return page, nil
+}
+
+func (m *memoryBooks) One(q *testBook) (testBook, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	b, ok := m.books[q.ID]
+	if !ok {
+		return b, ERR_NODATA
+	}
+	return b, nil
+}
+
+func (m *memoryBooks) Save(b *testBook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	m.nextID++
+	b.ID = m.nextID
+	m.books[b.ID] = *b
+	return nil
+}
+
+func (m *memoryBooks) Update(b *testBook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.books[b.ID]; !ok {
+		return ERR_NODATA
+	}
+	m.books[b.ID] = *b
+	return nil
+}
+
+// Patch 只更新非零值的字段，同 Service[T].Patch。
+func (m *memoryBooks) Patch(b *testBook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	old, ok := m.books[b.ID]
+	if !ok {
+		return ERR_NODATA
+	}
+	if b.Title == "" {
+		b.Title = old.Title
+	}
+	m.books[b.ID] = *b
+	return nil
+}
+
+func (m *memoryBooks) Delete(b *testBook) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+	if _, ok := m.books[b.ID]; !ok {
+		return ERR_NODATA
+	}
+	delete(m.books, b.ID)
+	return nil
+}
+
+func TestResource(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	engine := gin.New()
+	engine.Use(GlobalErrorHandler())
+	store := &memoryBooks{books: map[int64]testBook{}}
+	h := ResourceService[testBook](engine.Group("/api"), "/books", store)
+	h.Hooks.Authorize = func(ctx *gin.Context, op CrudOp, b *testBook) error {
+		if op == OpSave && b.Title == "forbidden" {
+			return ERR_FORBIDDEN
+		}
+		return nil
+	}
+
+	do := func(method, path, body string) (int, string) {
+		req := httptest.NewRequest(method, path, strings.NewReader(body))
+		if body != "" {
+			req.Header.Set("Content-Type", "application/json")
+		}
+		w := httptest.NewRecorder()
+		engine.ServeHTTP(w, req)
+		return w.Code, w.Body.String()
+	}
+
+	if code, body := do(http.MethodPost, "/api/books/batch", `[{"title":"a"},{"title":"b"},{"title":"c"}]`); code != http.StatusOK || !strings.Contains(body, `"id":3`) {
+		t.Fatalf("batch save: %d %s", code, body)
+	}
+	if code, body := do(http.MethodPut, "/api/books/2", `{"id":99,"title":"b2"}`); code != http.StatusOK || !strings.Contains(body, `"id":2`) {
+		t.Fatalf("update should take id from path: %d %s", code, body)
+	}
+	if code, body := do(http.MethodGet, "/api/books/2", ""); !strings.Contains(body, `"title":"b2"`) {
+		t.Fatalf("one: %d %s", code, body)
+	}
+	// PATCH 走 Patch，零值不覆盖；PUT 走 Update，零值照常写入。
+	if code, body := do(http.MethodPatch, "/api/books/2", `{"title":""}`); !strings.Contains(body, `"title":"b2"`) {
+		t.Fatalf("patch: %d %s", code, body)
+	}
+	if code, body := do(http.MethodPatch, "/api/books/batch", `[{"id":2}]`); !strings.Contains(body, `"title":"b2"`) {
+		t.Fatalf("batch patch: %d %s", code, body)
+	}
+	if code, body := do(http.MethodPut, "/api/books/2", `{"title":""}`); !strings.Contains(body, `"title":""`) {
+		t.Fatalf("put should write zero values: %d %s", code, body)
+	}
+	if code, body := do(http.MethodDelete, "/api/books/batch", `{"ids":[1,"3"]}`); code != http.StatusOK {
+		t.Fatalf("batch delete: %d %s", code, body)
+	}
+	if code, body := do(http.MethodDelete, "/api/books/1", ""); !strings.Contains(body, ERR_NODATA.Msg) {
+		t.Fatalf("delete missing: %d %s", code, body)
+	}
+	if code, body := do(http.MethodGet, "/api/books", ""); !strings.Contains(body, `"total":1`) {
+		t.Fatalf("page: %d %s", code, body)
+	}
+	if _, body := do(http.MethodGet, "/api/books/abc", ""); !strings.Contains(body, "id 无效") {
+		t.Fatalf("invalid id: %s", body)
+	}
+	if code, _ := do(http.MethodPost, "/api/books/batch", `[{"title":"d"},{"title":"forbidden"}]`); code != http.StatusForbidden {
+		t.Fatalf("batch authorize: %d", code)
+	}
+}
+
+// fakeTx 只实现 Commit / Rollback 的 pgx.Tx。
+type fakeTx struct {
+	pgx.Tx
+	commitErr  error
+	rolledBack bool
+}
+
+func (f *fakeTx) Commit(context.Context) error { return f.commitErr }
+func (f *fakeTx) Rollback(context.Context) error {
+	f.rolledBack = true
+	return nil
+}
+
+func TestWithTransactionCommitError(t *testing.T) {
+	ctx, _ := gin.CreateTestContext(httptest.NewRecorder())
+	failed, named := &fakeTx{commitErr: errors.New("serialization failure")}, &fakeTx{}
+	err := withTransaction(ctx, func() error {
+		tx := ctx.Value(TRANSACTION).(*Transaction)
+		tx.set("", failed)
+		tx.set("audit", named)
+		return nil
+	})
+	if err == nil || !strings.Contains(err.Error(), "serialization failure") {
+		t.Fatalf("commit error should be returned: %v", err)
+	}
+	if !named.rolledBack {
+		t.Fatal("remaining transactions should be rolled back after a failed commit")
+	}
+}
+
+// sharedService 所有请求共用 svc 的工厂，只适用于不依赖请求上事务的 Service。
+func sharedService[T any](svc CrudService[T]) func(*gin.Context) CrudService[T] {
+	return func(*gin.Context) CrudService[T] { return svc }
+}
+
+// stagingTx 模拟数据库事务：提交时才把暂存的记录写入 store。
+type stagingTx struct {
+	pgx.Tx
+	store  *memoryBooks
+	staged []testBook
+}
+
+func (s *stagingTx) Commit(context.Context) error {
+	for _, b := range s.staged {
+		s.store.Save(&b)
+	}
+	return nil
+}
+
+func (s *stagingTx) Rollback(context.Context) error { return nil }
+
+// stagedBooks 按请求创建的 Service，请求开启了事务时 Save 写入事务，title 为 fail 时失败。
+type stagedBooks struct {
+	*memoryBooks
+	ctx *gin.Context
+}
+
+func (s *stagedBooks) Save(b *testBook) error {
+	if b.Title == "fail" {
+		return ERR_PARAM
+	}
+	tx, _ := s.ctx.Value(TRANSACTION).(*Transaction)
+	if tx == nil || !tx.Begin {
+		return s.memoryBooks.Save(b)
+	}
+	staging, _ := tx.get("").(*stagingTx)
+	if staging == nil {
+		staging = &stagingTx{store: s.memoryBooks}
+		tx.set("", staging)
+	}
+	staging.staged = append(staging.staged, *b)
+	return nil
+}
+
+func TestResourceBatchIsAtomic(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	for _, withHandler := range []bool{false, true} {
+		engine := gin.New()
+		engine.Use(GlobalErrorHandler())
+		if withHandler {
+			engine.Use(TransactionHandler())
+		}
+		store := &memoryBooks{books: map[int64]testBook{}}
+		Resource(engine.Group(""), "/books", func(ctx *gin.Context) CrudService[testBook] {
+			return &stagedBooks{memoryBooks: store, ctx: ctx}
+		})
+		post := func(body string) int {
+			req := httptest.NewRequest(http.MethodPost, "/books/batch", strings.NewReader(body))
+			req.Header.Set("Content-Type", "application/json")
+			w := httptest.NewRecorder()
+			engine.ServeHTTP(w, req)
+			return w.Code
+		}
+		if post(`[{"title":"a"},{"title":"fail"}]`); len(store.books) != 0 {
+			t.Fatalf("TransactionHandler=%v: failed batch left %d rows", withHandler, len(store.books))
+		}
+		if code := post(`[{"title":"a"},{"title":"b"}]`); code != http.StatusOK || len(store.books) != 2 {
+			t.Fatalf("TransactionHandler=%v: batch saved %d rows (code %d)", withHandler, len(store.books), code)
+		}
+	}
+}
@@ -0,0 +1,287 @@
+package gowk
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"os/exec"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// 零停机重启（监听继承）：
+//   - 旧进程收到 SIGUSR2（或调用 Restart）后 re-exec 当前程序，把正在监听的 socket 通过 ExtraFiles 传给新进程；
+//   - 新进程的 HTTP / gRPC / HTTP/3 / 管理端口按地址优先复用继承的 socket，匹配不到才自行绑定；
+//   - 新进程 PostStart 钩子执行完后经 ready 管道通知旧进程，旧进程随即走正常关闭流程，由 ServerStop 排空存量请求；
+//   - 新进程启动失败、提前退出或超过 RESTART_READY_TIMEOUT 未就绪，旧进程继续服务。
+//
+// systemd socket activation（LISTEN_PID / LISTEN_FDS）同样作为继承来源，按地址匹配。
+
+const (
+	// envInheritFds 父进程传给新进程的 socket 数量，fd 从 listenFdsStart 开始连续排列。
+	envInheritFds = "GOWK_INHERIT_FDS"
+	// envReadyFd 新进程就绪后写入一个字节的管道 fd。
+	envReadyFd     = "GOWK_READY_FD"
+	listenFdsStart = 3
+)
+
+var restartRequests = make(chan struct{}, 1)
+
+// Restart 请求一次零停机重启，由正在运行的 RunContext 处理；重启进行中的重复请求会被忽略。
+func Restart() {
+	select {
+	case restartRequests <- struct{}{}:
+	default:
+	}
+}
+
+// inheritedSocket 继承来的 socket，ln 与 pc 二选一。
+type inheritedSocket struct {
+	ln net.Listener
+	pc net.PacketConn
+}
+
+func (s inheritedSocket) addr() net.Addr {
+	if s.ln != nil {
+		return s.ln.Addr()
+	}
+	return s.pc.LocalAddr()
+}
+
+// inherited 启动时继承、尚未被 listen / listenPacket 取走的 socket。
+var inherited struct {
+	once    sync.Once
+	mu      sync.Mutex
+	sockets []inheritedSocket
+}
+
+// active 本进程正在使用的 socket，重启时传给新进程。
+var active struct {
+	mu    sync.Mutex
+	files []interface{ File() (*os.File, error) }
+}
+
+// loadInherited 读取父进程或 systemd 传入的 fd，读取后清掉相关环境变量，避免再传给子进程。
+func loadInherited() {
+	var n int
+	if pid := os.Getenv("LISTEN_PID"); pid != "" && pid == strconv.Itoa(os.Getpid()) {
+		n, _ = strconv.Atoi(os.Getenv("LISTEN_FDS"))
+	} else if v := os.Getenv(envInheritFds); v != "" {
+		n, _ = strconv.Atoi(v)
+	}
+	for _, key := range []string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", envInheritFds} {
+		_ = os.Unsetenv(key)
+	}
+	for i := range n {
+		f := os.NewFile(uintptr(listenFdsStart+i), "inherited-"+strconv.Itoa(i))
+		if ln, err := net.FileListener(f); err == nil {
+			inherited.sockets = append(inherited.sockets, inheritedSocket{ln: ln})
+		} else if pc, err := net.FilePacketConn(f); err == nil {
+			inherited.sockets = append(inherited.sockets, inheritedSocket{pc: pc})
+		} else {
+			slog.Warn("忽略无法识别的继承 fd", "fd", listenFdsStart+i, "err", err)
+		}
+		// FileListener / FilePacketConn 内部会 dup，原 fd 可以关闭。
+		_ = f.Close()
+	}
+	if len(inherited.sockets) > 0 {
+		slog.Info("继承监听 socket", "count", len(inherited.sockets))
+	}
+}
+
+// takeInherited 取走与 network / addr 匹配的继承 socket，没有时返回零值。
+func takeInherited(network, addr string) inheritedSocket {
+	inherited.once.Do(loadInherited)
+	inherited.mu.Lock()
+	defer inherited.mu.Unlock()
+	for i, s := range inherited.sockets {
+		if sameAddr(s.addr(), network, addr) {
+			inherited.sockets = slices.Delete(inherited.sockets, i, i+1)
+			slog.Info("复用继承的监听", "network", network, "addr", s.addr().String())
+			return s
+		}
+	}
+	return inheritedSocket{}
+}
+
+// sameAddr 判断已绑定的地址 a 是否满足配置的 addr：端口必须相同（端口 0 不匹配），
+// 未指定 IP（":3030"、"0.0.0.0:3030"）只匹配通配地址；其他类型（如 unix）按字符串比较。
+func sameAddr(a net.Addr, network, addr string) bool {
+	switch a := a.(type) {
+	case *net.TCPAddr:
+		want, err := net.ResolveTCPAddr(network, addr)
+		return err == nil && sameIPPort(a.IP, a.Port, want.IP, want.Port)
+	case *net.UDPAddr:
+		want, err := net.ResolveUDPAddr(network, addr)
+		return err == nil && sameIPPort(a.IP, a.Port, want.IP, want.Port)
+	default:
+		return a.Network() == network && a.String() == addr
+	}
+}
+
+func sameIPPort(ip net.IP, port int, wantIP net.IP, wantPort int) bool {
+	if wantPort == 0 || port != wantPort {
+		return false
+	}
+	if wantIP == nil || wantIP.IsUnspecified() {
+		return ip.IsUnspecified()
+	}
+	return ip.Equal(wantIP)
+}
+
+// listen 绑定 HTTP / gRPC / 管理端口的地址，"unix:///path" 为 Unix socket，其余为 TCP。
+// 优先复用继承的 socket，否则新建；返回的 listener 在重启时会传给新进程。
+func listen(addr string) (net.Listener, error) {
+	network, address := "tcp", addr
+	if path, ok := strings.CutPrefix(addr, unixScheme); ok {
+		network, address = "unix", path
+	}
+	ln := takeInherited(network, address).ln
+	if ln == nil {
+		var err error
+		if network == "unix" {
+			ln, err = listenUnix(address)
+		} else {
+			ln, err = net.Listen(network, address)
+		}
+		if err != nil {
+			return nil, err
+		}
+	} else if ul, ok := ln.(*net.UnixListener); ok {
+		// FileListener 得到的 listener 默认关闭时不删除 socket 文件，接手后由本进程负责清理。
+		ul.SetUnlinkOnClose(true)
+	}
+	if f, ok := ln.(interface{ File() (*os.File, error) }); ok {
+		active.mu.Lock()
+		active.files = append(active.files, f)
+		active.mu.Unlock()
+	}
+	return ln, nil
+}
+
+// listenPacket 是 listen 的 UDP 版本，供 HTTP/3 使用。
+func listenPacket(network, addr string) (net.PacketConn, error) {
+	pc := takeInherited(network, addr).pc
+	if pc == nil {
+		var err error
+		if pc, err = net.ListenPacket(network, addr); err != nil {
+			return nil, err
+		}
+	}
+	if f, ok := pc.(interface{ File() (*os.File, error) }); ok {
+		active.mu.Lock()
+		active.files = append(active.files, f)
+		active.mu.Unlock()
+	}
+	return pc, nil
+}
+
+// activeFiles 复制本进程仍在使用的 socket fd，已关闭的顺带移除。
+func activeFiles() []*os.File {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	var files []*os.File
+	live := active.files[:0]
+	for _, s := range active.files {
+		f, err := s.File()
+		if err != nil {
+			continue
+		}
+		files = append(files, f)
+		live = append(live, s)
+	}
+	active.files = live
+	return files
+}
+
+// startRestart re-exec 当前程序并传入正在使用的 socket。
+// 返回的 channel 在新进程就绪时收到 nil，新进程退出或超时未就绪时收到 error（超时会杀掉新进程）。
+func startRestart() (<-chan error, error) {
+	files := activeFiles()
+	defer func() {
+		for _, f := range files {
+			_ = f.Close()
+		}
+	}()
+	if len(files) == 0 {
+		return nil, errors.New("没有可传给新进程的监听")
+	}
+	exe, err := os.Executable()
+	if err != nil {
+		return nil, fmt.Errorf("获取可执行文件路径失败: %w", err)
+	}
+	r, w, err := os.Pipe()
+	if err != nil {
+		return nil, fmt.Errorf("创建 ready 管道失败: %w", err)
+	}
+
+	cmd := exec.Command(exe, os.Args[1:]...)
+	cmd.Stdin, cmd.Stdout, cmd.Stderr = os.Stdin, os.Stdout, os.Stderr
+	cmd.ExtraFiles = append(slices.Clone(files), w)
+	cmd.Env = append(restartEnv(),
+		envInheritFds+"="+strconv.Itoa(len(files)),
+		envReadyFd+"="+strconv.Itoa(listenFdsStart+len(files)),
+	)
+	err = cmd.Start()
+	// 父进程不再持有写端，新进程退出时读端才能读到 EOF。
+	_ = w.Close()
+	if err != nil {
+		_ = r.Close()
+		return nil, fmt.Errorf("启动新进程失败: %w", err)
+	}
+	pid := cmd.Process.Pid
+	slog.Info("新进程已启动，等待就绪", "pid", pid, "listeners", len(files))
+	go func() { _ = cmd.Wait() }()
+
+	ready := make(chan error, 1)
+	go func() {
+		defer r.Close()
+		_ = r.SetReadDeadline(time.Now().Add(conf().Lifecycle.RestartReadyTimeout))
+		if _, err := r.Read(make([]byte, 1)); err != nil {
+			if errors.Is(err, os.ErrDeadlineExceeded) {
+				_ = cmd.Process.Kill()
+				ready <- fmt.Errorf("新进程 pid=%d 超过 %s 未就绪", pid, conf().Lifecycle.RestartReadyTimeout)
+				return
+			}
+			ready <- fmt.Errorf("新进程 pid=%d 未就绪即退出", pid)
+			return
+		}
+		// socket 文件已由新进程接手，旧进程关闭监听时不能删除。
+		keepUnixSocketFiles()
+		ready <- nil
+	}()
+	return ready, nil
+}
+
+// restartEnv 去掉继承相关的环境变量，由 startRestart 重新设置。
+func restartEnv() []string {
+	return slices.DeleteFunc(os.Environ(), func(kv string) bool {
+		key, _, _ := strings.Cut(kv, "=")
+		return slices.Contains([]string{"LISTEN_PID", "LISTEN_FDS", "LISTEN_FDNAMES", envInheritFds, envReadyFd}, key)
+	})
+}
+
+// notifyParentReady 通知旧进程本进程已就绪，可以开始排空；不是由 Restart 拉起时什么都不做。
+func notifyParentReady() {
+	v := os.Getenv(envReadyFd)
+	if v == "" {
+		return
+	}
+	_ = os.Unsetenv(envReadyFd)
+	fd, err := strconv.Atoi(v)
+	if err != nil || fd < listenFdsStart {
+		return
+	}
+	f := os.NewFile(uintptr(fd), "ready")
+	defer f.Close()
+	if _, err := f.Write([]byte{1}); err != nil {
+		slog.Warn("通知旧进程就绪失败", "err", err)
+		return
+	}
+	slog.Info("已通知旧进程就绪", "ppid", os.Getppid())
+}
@@ -0,0 +1,34 @@
+package gowk
+
+import (
+	"net"
+	"strconv"
+	"testing"
+)
+
+func TestTakeInherited(t *testing.T) {
+	inherited.once.Do(loadInherited)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	port := ln.Addr().(*net.TCPAddr).Port
+	inherited.sockets = append(inherited.sockets, inheritedSocket{ln: ln})
+	defer func() { inherited.sockets = nil }()
+
+	for _, addr := range []string{":0", "0.0.0.0:" + strconv.Itoa(port), "127.0.0.2:" + strconv.Itoa(port)} {
+		if s := takeInherited("tcp", addr); s.ln != nil {
+			t.Fatalf("%s should not match %s", addr, ln.Addr())
+		}
+	}
+	if s := takeInherited("udp", "127.0.0.1:"+strconv.Itoa(port)); s.ln != nil || s.pc != nil {
+		t.Fatal("udp should not match a tcp listener")
+	}
+	if s := takeInherited("tcp", "127.0.0.1:"+strconv.Itoa(port)); s.ln != ln {
+		t.Fatal("inherited listener not reused")
+	}
+	if s := takeInherited("tcp", "127.0.0.1:"+strconv.Itoa(port)); s.ln != nil {
+		t.Fatal("inherited listener reused twice")
+	}
+}
@@ -14,7 +14,8 @@ import (
 // backoff 语义：初始 sleep = base，每轮失败后 backoff *= 2，最终被 max 封顶；
 // max < base 时内部会把 max 抬到 base，退化为固定间隔 base。
 // 进程退出时调用方 cancel(ctx)，当前 sleep 与后续 attempt 立刻结束。
-func retryBackground(ctx context.Context, name string, base, max time.Duration, attempt func(context.Context) error) {
+// report 非 nil 时每轮 attempt 结束后回调（第几次、结果），用于刷新健康状态。
+func retryBackground(ctx context.Context, name string, base, max time.Duration, report func(int, error), attempt func(context.Context) error) {
 	if base <= 0 {
 		base = 2 * time.Second
 	}
@@ -34,6 +35,9 @@ func retryBackground(ctx context.Context, name string, base, max time.Duration,
 			return
 		}
 		err := attempt(ctx)
+		if report != nil && ctx.Err() == nil {
+			report(attemptN, err)
+		}
 		if err == nil {
 			slog.Info(name+" 连接成功",
 				"attempts", attemptN,
@@ -5,7 +5,9 @@ import (
 	"log/slog"
 	"os"
 	"os/signal"
+	"slices"
 	"syscall"
+	"time"
 
 	"github.com/gin-gonic/gin"
 )
@@ -13,48 +15,154 @@ import (
 type ServerConfig struct {
 	HttpEngine *gin.Engine
 	GrpcServer *GrpcServer
+	// Hooks 只对本次运行生效的生命周期钩子，与 RegisterHook 注册的全局钩子合并后按 Order 执行。
+	Hooks []Hook
+	// WaitFor 启动前必须就绪的依赖："postgres"、"postgres:<name>"（命名实例）、"redis" 或 RegisterHealthCheck 注册的检查名。
+	// 与 DATABASE_REQUIRED / REDIS_REQUIRED 合并；为空时不阻塞启动。
+	WaitFor []string
+	// WaitTimeout 等待 WaitFor 就绪的上限，<= 0 时取 STARTUP_WAIT_TIMEOUT（默认 30s）。
+	WaitTimeout time.Duration
 }
 
+// requiredDependencies 合并 ServerConfig.WaitFor 与环境变量声明的必需依赖，去重保序。
+func (c *ServerConfig) requiredDependencies() []string {
+	var names []string
+	if conf().Database.Required {
+		names = append(names, "postgres")
+	}
+	if conf().Redis.Required {
+		names = append(names, "redis")
+	}
+	names = append(names, c.WaitFor...)
+	seen := make(map[string]bool, len(names))
+	res := names[:0]
+	for _, n := range names {
+		if n == "" || seen[n] {
+			continue
+		}
+		seen[n] = true
+		res = append(res, n)
+	}
+	return res
+}
+
+// Run 监听 SIGINT / SIGTERM 运行服务，是 RunContext 的薄封装。
+// 另外监听 SIGUSR2 触发零停机重启（见 Restart）、SIGHUP 重载配置（见 ReloadConfig）。
+// 启动失败或 Serve 异常时打日志并 os.Exit(1)，交给 docker / K8s 重启。
 func Run(config *ServerConfig) {
+	// 同时监听 SIGINT（Ctrl+C）和 SIGTERM（Docker/K8s 停止信号）
+	ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt, syscall.SIGTERM)
+	defer stop()
+	watchSignals(ctx)
+	if err := RunContext(ctx, config); err != nil {
+		slog.Error("服务异常退出", "err", err)
+		os.Exit(1)
+	}
+}
+
+// RunContext 启动 HTTP / gRPC 并阻塞，直到 ctx 被取消或某个 Server 的 Serve 异常退出，随后优雅关闭。
+// 监听失败、启动钩子失败与 Serve 异常都以 error 返回（返回前已完成清理），ctx 取消导致的正常关闭返回 nil。
+// 不注册信号、不调用 os.Exit，便于嵌入其他进程或在测试中驱动启停。
+//
+// 执行顺序：检查配置（含游标分页密钥）→ InitPostgres → 管理端口监听 → 等待必需依赖 → PreStart 钩子 → HTTP / gRPC 监听 → PostStart 钩子
+// → 等待退出（ctx 取消 / Serve 异常 / 零停机重启的新进程就绪） → PreShutdown 钩子 → 停 gRPC / HTTP → PostShutdown 钩子 → closePostgres / closeRedis → 停管理端口。
+func RunContext(ctx context.Context, config *ServerConfig) error {
+	// 环境变量在包初始化时解析，错误推迟到这里返回；LoadConfig 成功后会清除。
+	if err := configError(); err != nil {
+		return err
+	}
+	if err := checkCursorSecret(); err != nil {
+		return err
+	}
+
 	// 触发 Postgres 后台初始化（非阻塞，连不上也不退出，后台退避重试）。
 	// Redis 保持按需：首次 Redis() / InitRedis() 时才触发后台初始化。
 	InitPostgres()
 
-	var httpServer *HttpServer
-	var grpcServer *GrpcServer
+	// 管理端口最先起、最后停，启动卡在等待依赖时也能排查。
+	admin := &AdminServer{}
+	cleanup := func() {
+		closePostgres()
+		closeRedis()
+		admin.ServerStop()
+	}
+	if err := admin.ServerRun(); err != nil {
+		cleanup()
+		return err
+	}
+	// 配置文件变化时自动重载，随 ctx 结束。
+	go watchConfigFiles(ctx)
 
-	if config.HttpEngine != nil {
-		httpServer = &HttpServer{Engine: config.HttpEngine}
-		if err := httpServer.ServerRun(); err != nil {
-			slog.Error("HTTP 启动失败，进程退出", "err", err)
-			// 监听都没成功，无需 Shutdown HTTP；顺手清理已触发的依赖初始化。
-			closePostgres()
-			closeRedis()
-			os.Exit(1)
-		}
+	required := config.requiredDependencies()
+	if slices.Contains(required, "redis") {
+		InitRedis()
+	}
+	if err := waitForDependencies(ctx, required, config.WaitTimeout); err != nil {
+		cleanup()
+		return err
 	}
 
-	if config.GrpcServer != nil {
-		grpcServer = config.GrpcServer
-		if err := grpcServer.ServerRun(); err != nil {
-			slog.Error("gRPC 启动失败，进程退出", "err", err)
-			// HTTP 可能已经起来了，先优雅关掉避免端口残留。
-			if httpServer != nil {
-				httpServer.ServerStop()
+	if err := runHooks(ctx, config, PreStart, true); err != nil {
+		cleanup()
+		return err
+	}
+
+	httpServer, grpcServer, err := startServers(config)
+	if err != nil {
+		// 监听没成功，顺手清理已触发的依赖初始化。
+		cleanup()
+		return err
+	}
+	var httpErrs, grpcErrs <-chan error
+	if httpServer != nil {
+		httpErrs = httpServer.serveErrors()
+	}
+	if grpcServer != nil {
+		grpcErrs = grpcServer.serveErrors()
+	}
+
+	// PostStart 失败时 Server 已在服务，走完整关闭流程，让已启动的钩子有机会收尾。
+	runErr := runHooks(ctx, config, PostStart, true)
+	if runErr == nil {
+		// 由 Restart 拉起的新进程到这里才算就绪，旧进程收到通知后开始排空。
+		notifyParentReady()
+		var restartReady <-chan error
+	wait:
+		for {
+			select {
+			case <-ctx.Done():
+				break wait
+			case runErr = <-httpErrs:
+				break wait
+			case runErr = <-grpcErrs:
+				break wait
+			case runErr = <-admin.serveErrors():
+				break wait
+			case <-restartRequests:
+				if restartReady != nil {
+					slog.Warn("零停机重启进行中，忽略重复请求")
+					continue
+				}
+				if restartReady, err = startRestart(); err != nil {
+					slog.Error("零停机重启失败，继续服务", "err", err)
+				}
+			case err := <-restartReady:
+				restartReady = nil
+				if err != nil {
+					slog.Error("零停机重启失败，继续服务", "err", err)
+					continue
+				}
+				slog.Info("新进程已就绪，旧进程开始排空")
+				break wait
 			}
-			closePostgres()
-			closeRedis()
-			os.Exit(1)
 		}
 	}
 
-	quit := make(chan os.Signal, 1)
-	// 同时监听 SIGINT（Ctrl+C）和 SIGTERM（Docker/K8s 停止信号）
-	signal.Notify(quit, os.Interrupt, syscall.SIGTERM)
-	<-quit
-
 	slog.Info("Shutting down servers...")
 
+	// 关闭阶段 ctx 多半已取消，钩子使用独立的 Background 上下文，超时由各自 Timeout 控制。
+	_ = runHooks(context.Background(), config, PreShutdown, false)
+
 	if grpcServer != nil {
 		grpcServer.ServerStop()
 		slog.Info("gRPC server stopped")
@@ -64,9 +172,42 @@ func Run(config *ServerConfig) {
 		slog.Info("HTTP server stopped")
 	}
 
-	closePostgres()
-	closeRedis()
+	_ = runHooks(context.Background(), config, PostShutdown, false)
+
+	cleanup()
 	slog.Info("All servers stopped")
+	return runErr
+}
+
+// startServers 按配置绑定端口并在后台 Serve；HTTP 与 gRPC 地址相同时走单端口模式。
+// 返回 error 时已启动的 Server 均已关闭。
+func startServers(config *ServerConfig) (*HttpServer, *GrpcServer, error) {
+	if config.HttpEngine != nil && config.GrpcServer != nil && singlePort() {
+		httpServer := &HttpServer{Engine: config.HttpEngine}
+		if err := serveSinglePort(httpServer, config.GrpcServer); err != nil {
+			return nil, nil, err
+		}
+		return httpServer, config.GrpcServer, nil
+	}
+
+	var httpServer *HttpServer
+	if config.HttpEngine != nil {
+		httpServer = &HttpServer{Engine: config.HttpEngine}
+		if err := httpServer.ServerRun(); err != nil {
+			// 监听都没成功，无需 Shutdown HTTP。
+			return nil, nil, err
+		}
+	}
+	if config.GrpcServer != nil {
+		if err := config.GrpcServer.ServerRun(); err != nil {
+			// HTTP 可能已经起来了，先优雅关掉避免端口残留。
+			if httpServer != nil {
+				httpServer.ServerStop()
+			}
+			return nil, nil, err
+		}
+	}
+	return httpServer, config.GrpcServer, nil
 }
 
 func RunHTTP(engine *gin.Engine) {
@@ -81,11 +222,18 @@ func RunBoth(engine *gin.Engine, grpcServer *GrpcServer) {
 	Run(&ServerConfig{HttpEngine: engine, GrpcServer: grpcServer})
 }
 
-// InitPostgres 触发 Postgres 的 sync.Once 初始化路径。
+// InitPostgres 触发默认实例、所有已配置命名实例与只读副本的 sync.Once 初始化路径。
 // 只会启动后台重试 goroutine，本身非阻塞；连接失败不会退出进程，
-// 在连接成功之前 Postgres(ctx) 返回 nil，PostgresTx 返回 "postgres unavailable"。
+// 在连接成功之前 Postgres(ctx) / PostgresNamed(ctx, name) 返回 nil，PostgresTx 返回 "postgres unavailable"。
 func InitPostgres() {
-	Postgres(context.Background())
+	ctx := context.Background()
+	Postgres(ctx)
+	for name := range conf().Database.Named {
+		PostgresNamed(ctx, name)
+	}
+	for _, p := range postgresReplicas() {
+		p.get()
+	}
 }
 
 // InitRedis 触发 Redis 的 sync.Once 初始化路径。
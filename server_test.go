@@ -0,0 +1,108 @@
+package gowk
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestRunContextShutdown(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := conf().Server.HTTPAddr
+	SetHTTPServerAddr("127.0.0.1:0")
+	defer SetHTTPServerAddr(prev)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- RunContext(ctx, &ServerConfig{HttpEngine: gin.New()}) }()
+
+	time.Sleep(50 * time.Millisecond)
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatalf("RunContext returned %v, want nil", err)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("RunContext did not return after cancel")
+	}
+}
+
+func TestRunContextListenError(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	ln, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer ln.Close()
+	prev := conf().Server.HTTPAddr
+	SetHTTPServerAddr(ln.Addr().String())
+	defer SetHTTPServerAddr(prev)
+
+	if err := RunContext(context.Background(), &ServerConfig{HttpEngine: gin.New()}); err == nil {
+		t.Fatal("expected listen error on occupied port")
+	}
+}
+
+func TestRunContextHooks(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := conf().Server.HTTPAddr
+	SetHTTPServerAddr("127.0.0.1:0")
+	defer SetHTTPServerAddr(prev)
+
+	var order []string
+	record := func(name string) func(context.Context) error {
+		return func(context.Context) error {
+			order = append(order, name)
+			return nil
+		}
+	}
+	ctx, cancel := context.WithCancel(context.Background())
+	config := &ServerConfig{
+		HttpEngine: gin.New(),
+		Hooks: []Hook{
+			{Name: "flush", Phase: PostShutdown, Fn: record("flush")},
+			{Name: "drain", Phase: PreShutdown, Fn: record("drain")},
+			{Name: "consumer", Phase: PostStart, Order: 2, Fn: record("consumer")},
+			{Name: "warmup", Phase: PostStart, Order: 1, Fn: record("warmup")},
+			{Name: "migrate", Phase: PreStart, Fn: record("migrate")},
+		},
+	}
+	go func() {
+		time.Sleep(50 * time.Millisecond)
+		cancel()
+	}()
+	if err := RunContext(ctx, config); err != nil {
+		t.Fatal(err)
+	}
+	want := []string{"migrate", "warmup", "consumer", "drain", "flush"}
+	if len(order) != len(want) {
+		t.Fatalf("order = %v, want %v", order, want)
+	}
+	for i := range want {
+		if order[i] != want[i] {
+			t.Fatalf("order = %v, want %v", order, want)
+		}
+	}
+}
+
+func TestRunContextStartHookAbort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	prev := conf().Server.HTTPAddr
+	SetHTTPServerAddr("127.0.0.1:0")
+	defer SetHTTPServerAddr(prev)
+
+	config := &ServerConfig{
+		HttpEngine: gin.New(),
+		Hooks: []Hook{{Name: "slow", Phase: PreStart, Timeout: 20 * time.Millisecond, Fn: func(context.Context) error {
+			time.Sleep(time.Second)
+			return nil
+		}}},
+	}
+	if err := RunContext(context.Background(), config); err == nil {
+		t.Fatal("expected pre-start timeout to abort startup")
+	}
+}
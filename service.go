@@ -1,37 +1,61 @@
 package gowk
 
 import (
+	"context"
+
 	"github.com/gin-gonic/gin"
 )
 
-// Service 提供泛型 CRUD 基础结构，方法需由业务层结合具体 DB 驱动实现。
-// 框架目前使用 pgxpool（非 GORM），因此此处仅定义接口骨架，
-// 业务层可通过嵌套 Service[T] 并重写方法来扩展。
+// Service 提供泛型 CRUD 的默认实现，基于 Repository[T] 按 T 的 struct tag 读写 Postgres，
+// 请求开启了事务（Begin）时走事务。业务层可嵌套 Service[T] 并重写方法，再通过 NewHandler 的工厂注入，见 CrudService。
 type Service[T any] struct {
-	Ctx *gin.Context
+	Ctx  *gin.Context
+	Repo *Repository[T]
 }
 
 func NewService[T any](ctx *gin.Context) *Service[T] {
-	return &Service[T]{Ctx: ctx}
+	return &Service[T]{Ctx: ctx, Repo: NewRepository[T]()}
+}
+
+// context 没有请求上下文（NewService[T](nil)）时使用 context.Background()，此时不会走请求上的事务。
+func (s *Service[T]) context() context.Context {
+	if s.Ctx == nil {
+		return context.Background()
+	}
+	return s.Ctx
 }
 
-// Page 列表分页查询，业务层须重写此方法以提供具体实现。
+// Page 列表分页查询，queryParam 中非零值的字段作为等值条件，见 Repository.Page。
 func (s *Service[T]) Page(pageModel *PageModel[T], queryParam *T) (*PageModel[T], error) {
-	return pageModel, nil
+	return s.Repo.Page(s.context(), pageModel, queryParam)
+}
+
+// CursorPage 游标分页查询，条件同 Page，见 Repository.CursorPage。
+func (s *Service[T]) CursorPage(page *CursorPage[T], queryParam *T) (*CursorPage[T], error) {
+	return s.Repo.CursorPage(s.context(), page, queryParam)
 }
 
-// One 单条查询，业务层须重写此方法以提供具体实现。
+// One 单条查询，queryParam 中非零值的字段作为等值条件，没有时返回 ERR_NODATA。
 func (s *Service[T]) One(queryParam *T) (T, error) {
-	var model T
-	return model, nil
+	return s.Repo.One(s.context(), queryParam)
 }
 
-// Update 更新操作，业务层须重写此方法以提供具体实现。
+// Update 按主键更新所有字段（零值也会写入），见 Repository.Update。
 func (s *Service[T]) Update(postParam *T) error {
-	return nil
+	return s.Repo.Update(s.context(), postParam)
 }
 
-// Save 新增操作，业务层须重写此方法以提供具体实现。
+// Patch 按主键只更新非零值的字段，见 Repository.Patch。
+func (s *Service[T]) Patch(postParam *T) error {
+	return s.Repo.Patch(s.context(), postParam)
+}
+
+// Save 新增一条记录，并回填自增 id 等数据库生成的值。
 func (s *Service[T]) Save(postParam *T) error {
-	return nil
+	return s.Repo.Save(s.context(), postParam)
+}
+
+// Delete 按主键删除，记录不存在时返回 ERR_NODATA。
+func (s *Service[T]) Delete(postParam *T) error {
+	return s.Repo.Delete(s.context(), postParam)
 }
@@ -0,0 +1,8 @@
+//go:build !unix
+
+package gowk
+
+import "context"
+
+// watchSignals 非 unix 平台没有 SIGUSR2 / SIGHUP，只能调用 Restart / ReloadConfig 触发。
+func watchSignals(ctx context.Context) {}
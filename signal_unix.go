@@ -0,0 +1,37 @@
+//go:build unix
+
+package gowk
+
+import (
+	"context"
+	"log/slog"
+	"os"
+	"os/signal"
+	"syscall"
+)
+
+// watchSignals 把 SIGUSR2 转为 Restart 请求、SIGHUP 转为 ReloadConfig，ctx 结束后停止监听。
+func watchSignals(ctx context.Context) {
+	ch := make(chan os.Signal, 1)
+	signal.Notify(ch, syscall.SIGUSR2, syscall.SIGHUP)
+	go func() {
+		defer signal.Stop(ch)
+		for {
+			select {
+			case <-ctx.Done():
+				return
+			case sig := <-ch:
+				switch sig {
+				case syscall.SIGUSR2:
+					slog.Info("收到 SIGUSR2，开始零停机重启")
+					Restart()
+				case syscall.SIGHUP:
+					slog.Info("收到 SIGHUP，重载配置")
+					if err := ReloadConfig(); err != nil {
+						slog.Error("配置重载失败，保持原配置", "err", err)
+					}
+				}
+			}
+		}
+	}()
+}
@@ -0,0 +1,274 @@
+package gowk
+
+import (
+	"bytes"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/net/http2"
+	"golang.org/x/net/http2/hpack"
+)
+
+// singlePort 判断 HTTP 与 gRPC 是否共用一个端口：两者地址相同即视为单端口模式。
+func singlePort() bool {
+	return HasGRPC() && conf().Server.GRPCAddr == conf().Server.HTTPAddr
+}
+
+// serveSinglePort 在一个监听上同时服务 HTTP 与 gRPC。
+// 按连接嗅探协议：HTTP/2 且首个请求 content-type 为 application/grpc 的连接交给 gRPC，其余交给 gin。
+// 两个 Server 各自拿到一个虚拟 listener，ServerStop 的语义（Shutdown / GracefulStop）保持不变；
+// 两个虚拟 listener 都关闭后底层端口才释放。
+//
+// 启用 TLS 时在共享端口上统一终止 TLS，之后 HTTP 与 gRPC 都看到明文连接：
+// gin 的 Request.TLS 为 nil，gRPC 的 peer 中也没有证书信息。
+func serveSinglePort(h *HttpServer, g *GrpcServer) error {
+	if g.initErr != nil {
+		return g.initErr
+	}
+	tlsConfig, err := h.prepare()
+	if err != nil {
+		return err
+	}
+	// 明文 HTTP/2（h2c prior knowledge）同样会被路由到 gin，需要显式打开。
+	if h.Handler.Protocols == nil {
+		h.Handler.Protocols = new(http.Protocols)
+		h.Handler.Protocols.SetHTTP1(true)
+		h.Handler.Protocols.SetUnencryptedHTTP2(true)
+	}
+	ln, err := listen(h.Handler.Addr)
+	if err != nil {
+		return fmt.Errorf("HTTP/gRPC 共用端口监听失败 addr=%s: %w", h.Handler.Addr, err)
+	}
+	if err := h.startHTTP3(tlsConfig); err != nil {
+		_ = ln.Close()
+		return err
+	}
+	if tlsConfig != nil {
+		ln = tls.NewListener(ln, tlsConfig)
+	}
+	m := newProtocolMux(ln)
+	g.serve(m.grpc)
+	h.serve(m.http, tlsConfig != nil)
+	go m.serve()
+	slog.Info("HTTP 与 gRPC 共用端口", "addr", ln.Addr().String())
+	return nil
+}
+
+const http2ClientPreface = "PRI * HTTP/2.0\r\n\r\nSM\r\n\r\n"
+
+// protocolMux 从一个 listener 接收连接，嗅探后分发到 http / grpc 两个虚拟 listener。
+type protocolMux struct {
+	root net.Listener
+	http *muxListener
+	grpc *muxListener
+
+	mu     sync.Mutex
+	closed int
+}
+
+func newProtocolMux(root net.Listener) *protocolMux {
+	m := &protocolMux{root: root}
+	m.http = newMuxListener(m)
+	m.grpc = newMuxListener(m)
+	return m
+}
+
+func (m *protocolMux) serve() {
+	for {
+		conn, err := m.root.Accept()
+		if err != nil {
+			if !errors.Is(err, net.ErrClosed) {
+				slog.Error("共用端口 accept 失败", "err", err)
+			}
+			m.http.shutdown()
+			m.grpc.shutdown()
+			return
+		}
+		// 嗅探放到独立 goroutine，慢客户端不阻塞 accept。
+		go m.dispatch(conn)
+	}
+}
+
+func (m *protocolMux) dispatch(conn net.Conn) {
+	_ = conn.SetReadDeadline(time.Now().Add(conf().Server.SniffTimeout))
+	sniffed, isGrpc, err := sniffGrpc(conn)
+	_ = conn.SetReadDeadline(time.Time{})
+	if err != nil {
+		_ = conn.Close()
+		return
+	}
+	target := m.http
+	if isGrpc {
+		target = m.grpc
+	}
+	if !target.deliver(sniffed) {
+		_ = conn.Close()
+	}
+}
+
+// childClosed 在虚拟 listener 关闭时调用，两个都关闭后释放底层端口。
+func (m *protocolMux) childClosed() {
+	m.mu.Lock()
+	m.closed++
+	all := m.closed == 2
+	m.mu.Unlock()
+	if all {
+		_ = m.root.Close()
+	}
+}
+
+// sniffGrpc 读取连接开头判断是否为 gRPC，返回的连接会重放已读取的字节。
+//
+// grpc-go 客户端在收到服务端 SETTINGS 之前不会发送请求头，因此匹配到 HTTP/2 前言后
+// 先代为写出一个空 SETTINGS 帧，再读取首个 HEADERS 判断 content-type。
+// 客户端会对这个 SETTINGS 回 ACK：gRPC Server 会忽略多余的 ACK，
+// 而 net/http 会视为协议错误，所以分给 HTTP 的连接要滤掉客户端的第一个 SETTINGS ACK。
+func sniffGrpc(conn net.Conn) (net.Conn, bool, error) {
+	var buf bytes.Buffer
+	r := io.TeeReader(conn, &buf)
+
+	// 逐步比对 HTTP/2 连接前言：一旦不匹配即判定为 HTTP/1.x，避免短请求等满 24 字节。
+	preface := make([]byte, 0, len(http2ClientPreface))
+	chunk := make([]byte, len(http2ClientPreface))
+	for len(preface) < len(http2ClientPreface) {
+		n, err := r.Read(chunk[:len(http2ClientPreface)-len(preface)])
+		preface = append(preface, chunk[:n]...)
+		if !strings.HasPrefix(http2ClientPreface, string(preface)) {
+			return &replayConn{Conn: conn, r: io.MultiReader(bytes.NewReader(buf.Bytes()), conn)}, false, nil
+		}
+		if err != nil {
+			return nil, false, err
+		}
+	}
+
+	framer := http2.NewFramer(conn, r)
+	framer.ReadMetaHeaders = hpack.NewDecoder(4096, nil)
+	if err := framer.WriteSettings(); err != nil {
+		return nil, false, err
+	}
+	for {
+		f, err := framer.ReadFrame()
+		if err != nil {
+			return nil, false, err
+		}
+		hf, ok := f.(*http2.MetaHeadersFrame)
+		if !ok {
+			continue
+		}
+		ct := ""
+		for _, field := range hf.RegularFields() {
+			if field.Name == "content-type" {
+				ct = field.Value
+				break
+			}
+		}
+		frames := io.MultiReader(bytes.NewReader(buf.Bytes()[len(http2ClientPreface):]), conn)
+		if strings.HasPrefix(ct, "application/grpc") {
+			return &replayConn{Conn: conn, r: io.MultiReader(strings.NewReader(http2ClientPreface), frames)}, true, nil
+		}
+		return &replayConn{Conn: conn, r: io.MultiReader(strings.NewReader(http2ClientPreface), &settingsAckFilter{r: frames})}, false, nil
+	}
+}
+
+type replayConn struct {
+	net.Conn
+	r io.Reader
+}
+
+func (c *replayConn) Read(p []byte) (int, error) {
+	return c.r.Read(p)
+}
+
+// settingsAckFilter 按帧透传 HTTP/2 数据，丢弃第一个 SETTINGS ACK 之后退化为直接读取。
+type settingsAckFilter struct {
+	r       io.Reader
+	dropped bool
+	pending []byte
+	remain  int
+}
+
+func (f *settingsAckFilter) Read(p []byte) (int, error) {
+	for len(f.pending) == 0 {
+		if f.dropped {
+			return f.r.Read(p)
+		}
+		if f.remain > 0 {
+			n, err := f.r.Read(p[:min(len(p), f.remain)])
+			f.remain -= n
+			return n, err
+		}
+		hdr := make([]byte, 9)
+		if _, err := io.ReadFull(f.r, hdr); err != nil {
+			return 0, err
+		}
+		length := int(hdr[0])<<16 | int(hdr[1])<<8 | int(hdr[2])
+		if http2.FrameType(hdr[3]) == http2.FrameSettings && http2.Flags(hdr[4]).Has(http2.FlagSettingsAck) && length == 0 {
+			f.dropped = true
+			continue
+		}
+		f.pending = hdr
+		f.remain = length
+	}
+	n := copy(p, f.pending)
+	f.pending = f.pending[n:]
+	return n, nil
+}
+
+// muxListener 是 protocolMux 分发出来的虚拟 listener。
+type muxListener struct {
+	mux   *protocolMux
+	conns chan net.Conn
+	done  chan struct{}
+	once  sync.Once
+}
+
+func newMuxListener(m *protocolMux) *muxListener {
+	return &muxListener{mux: m, conns: make(chan net.Conn), done: make(chan struct{})}
+}
+
+func (l *muxListener) deliver(conn net.Conn) bool {
+	select {
+	case l.conns <- conn:
+		return true
+	case <-l.done:
+		return false
+	}
+}
+
+func (l *muxListener) Accept() (net.Conn, error) {
+	select {
+	case conn := <-l.conns:
+		return conn, nil
+	case <-l.done:
+		return nil, net.ErrClosed
+	}
+}
+
+// shutdown 关闭虚拟 listener 但不计入 childClosed，用于底层 listener 已经失效的场景。
+func (l *muxListener) shutdown() {
+	l.once.Do(func() { close(l.done) })
+}
+
+func (l *muxListener) Close() error {
+	closed := false
+	l.once.Do(func() {
+		close(l.done)
+		closed = true
+	})
+	if closed {
+		l.mux.childClosed()
+	}
+	return nil
+}
+
+func (l *muxListener) Addr() net.Addr {
+	return l.mux.root.Addr()
+}
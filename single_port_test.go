@@ -0,0 +1,80 @@
+package gowk
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func TestSinglePort(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	addr := freeAddr(t)
+	prevHTTP, prevGRPC := conf().Server.HTTPAddr, conf().Server.GRPCAddr
+	SetHTTPServerAddr(addr)
+	SetGRPCServerAddr(addr)
+	defer func() {
+		SetHTTPServerAddr(prevHTTP)
+		SetGRPCServerAddr(prevGRPC)
+	}()
+
+	engine := gin.New()
+	engine.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- RunContext(ctx, &ServerConfig{HttpEngine: engine, GrpcServer: NewGrpcServer()}) }()
+	time.Sleep(100 * time.Millisecond)
+
+	resp, err := http.Get("http://" + addr + "/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("http body = %q", body)
+	}
+
+	// h2c prior knowledge 的普通 HTTP/2 请求也应交给 gin。
+	var protocols http.Protocols
+	protocols.SetUnencryptedHTTP2(true)
+	h2c := &http.Client{Transport: &http.Transport{Protocols: &protocols}}
+	for i := 0; i < 2; i++ {
+		resp, err = h2c.Get("http://" + addr + "/ping")
+		if err != nil {
+			t.Fatal(err)
+		}
+		body, _ = io.ReadAll(resp.Body)
+		resp.Body.Close()
+		if resp.ProtoMajor != 2 || string(body) != "pong" {
+			t.Fatalf("h2c: proto=%s body=%q", resp.Proto, body)
+		}
+	}
+
+	conn, err := grpc.NewClient(addr, grpc.WithTransportCredentials(insecure.NewCredentials()))
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer conn.Close()
+	rpcCtx, rpcCancel := context.WithTimeout(context.Background(), 2*time.Second)
+	defer rpcCancel()
+	if _, err := healthpb.NewHealthClient(conn).Check(rpcCtx, &healthpb.HealthCheckRequest{}); err != nil {
+		t.Fatalf("grpc health check over shared port: %v", err)
+	}
+
+	cancel()
+	select {
+	case err := <-done:
+		if err != nil {
+			t.Fatal(err)
+		}
+	case <-time.After(15 * time.Second):
+		t.Fatal("RunContext did not stop")
+	}
+}
@@ -0,0 +1,225 @@
+package gowk
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+)
+
+// TLSOptions 证书配置。CertFile / KeyFile 同时非空才启用 TLS；
+// ClientCAFile 非空时校验客户端证书（mTLS），ClientAuth 未设置时默认 RequireAndVerifyClientCert。
+type TLSOptions struct {
+	CertFile     string
+	KeyFile      string
+	ClientCAFile string
+	ClientAuth   tls.ClientAuthType
+	MinVersion   uint16
+}
+
+func (o *TLSOptions) enabled() bool {
+	return o != nil && o.CertFile != "" && o.KeyFile != ""
+}
+
+func (o *TLSOptions) clientAuth() tls.ClientAuthType {
+	if o.ClientAuth == tls.NoClientCert && o.ClientCAFile != "" {
+		return tls.RequireAndVerifyClientCert
+	}
+	return o.ClientAuth
+}
+
+var defaultTLS *TLSOptions
+
+// SetTLS 设置 HTTP / gRPC 默认使用的证书配置，优先级高于 Config.TLS。
+// 需在 NewGrpcServer / Run 之前调用；传 nil 恢复为 Config.TLS。
+func SetTLS(opts *TLSOptions) { defaultTLS = opts }
+
+// tlsOptions 返回生效的默认证书配置：SetTLS > Config.TLS（配置文件 / TLS_* 环境变量）；未配置返回 nil。
+func tlsOptions() *TLSOptions {
+	if defaultTLS != nil {
+		return defaultTLS
+	}
+	opts := &TLSOptions{
+		CertFile:     conf().TLS.CertFile,
+		KeyFile:      conf().TLS.KeyFile,
+		ClientCAFile: conf().TLS.ClientCAFile,
+	}
+	if !opts.enabled() {
+		return nil
+	}
+	auth, err := parseClientAuth(conf().TLS.ClientAuth)
+	if err != nil {
+		slog.Error("TLS_CLIENT_AUTH 配置无效，按默认处理", "value", conf().TLS.ClientAuth, "err", err)
+	}
+	opts.ClientAuth = auth
+	return opts
+}
+
+func parseClientAuth(s string) (tls.ClientAuthType, error) {
+	switch strings.ToLower(s) {
+	case "", "none":
+		return tls.NoClientCert, nil
+	case "request":
+		return tls.RequestClientCert, nil
+	case "require":
+		return tls.RequireAnyClientCert, nil
+	case "verify-if-given":
+		return tls.VerifyClientCertIfGiven, nil
+	case "require-and-verify":
+		return tls.RequireAndVerifyClientCert, nil
+	}
+	return tls.NoClientCert, fmt.Errorf("unknown client auth %q", s)
+}
+
+// certReloader 持有当前证书与客户端 CA，握手时按 TLS_RELOAD_INTERVAL 节流检查文件 mtime，
+// 变化后重新加载；加载失败保留旧证书并记日志，不影响已有服务。
+type certReloader struct {
+	opts TLSOptions
+
+	mu        sync.RWMutex
+	cert      *tls.Certificate
+	clientCAs *x509.CertPool
+	modTimes  []time.Time
+	lastCheck time.Time
+}
+
+func newCertReloader(opts *TLSOptions) (*certReloader, error) {
+	if !opts.enabled() {
+		return nil, errors.New("TLS 证书未配置")
+	}
+	r := &certReloader{opts: *opts}
+	if err := r.load(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *certReloader) files() []string {
+	files := []string{r.opts.CertFile, r.opts.KeyFile}
+	if r.opts.ClientCAFile != "" {
+		files = append(files, r.opts.ClientCAFile)
+	}
+	return files
+}
+
+func (r *certReloader) statFiles() ([]time.Time, error) {
+	files := r.files()
+	mods := make([]time.Time, len(files))
+	for i, f := range files {
+		fi, err := os.Stat(f)
+		if err != nil {
+			return nil, err
+		}
+		mods[i] = fi.ModTime()
+	}
+	return mods, nil
+}
+
+func (r *certReloader) load() error {
+	mods, err := r.statFiles()
+	if err != nil {
+		return fmt.Errorf("读取证书失败: %w", err)
+	}
+	cert, err := tls.LoadX509KeyPair(r.opts.CertFile, r.opts.KeyFile)
+	if err != nil {
+		return fmt.Errorf("加载证书失败: %w", err)
+	}
+	var pool *x509.CertPool
+	if r.opts.ClientCAFile != "" {
+		pem, err := os.ReadFile(r.opts.ClientCAFile)
+		if err != nil {
+			return fmt.Errorf("读取客户端 CA 失败: %w", err)
+		}
+		pool = x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return fmt.Errorf("客户端 CA 无有效证书: %s", r.opts.ClientCAFile)
+		}
+	}
+	r.mu.Lock()
+	r.cert = &cert
+	r.clientCAs = pool
+	r.modTimes = mods
+	r.lastCheck = time.Now()
+	r.mu.Unlock()
+	return nil
+}
+
+// maybeReload 距上次检查超过 conf().TLS.ReloadInterval 时比对 mtime，变化则重新加载。
+func (r *certReloader) maybeReload() {
+	r.mu.Lock()
+	if time.Since(r.lastCheck) < conf().TLS.ReloadInterval {
+		r.mu.Unlock()
+		return
+	}
+	r.lastCheck = time.Now()
+	prev := r.modTimes
+	r.mu.Unlock()
+
+	mods, err := r.statFiles()
+	if err != nil {
+		slog.Error("TLS 证书检查失败，继续使用旧证书", "err", err)
+		return
+	}
+	changed := false
+	for i := range mods {
+		if !mods[i].Equal(prev[i]) {
+			changed = true
+			break
+		}
+	}
+	if !changed {
+		return
+	}
+	if err := r.load(); err != nil {
+		slog.Error("TLS 证书热加载失败，继续使用旧证书", "err", err)
+		return
+	}
+	slog.Info("TLS 证书已重新加载", "cert", r.opts.CertFile)
+}
+
+// config 生成服务端 tls.Config。每次握手经 GetConfigForClient 取最新证书与 CA，
+// nextProtos 需由调用方给出（HTTP 为 h2/http1.1，gRPC 为 h2），否则 ALPN 协商会丢失。
+func (r *certReloader) config(nextProtos ...string) *tls.Config {
+	minVersion := r.opts.MinVersion
+	if minVersion == 0 {
+		minVersion = tls.VersionTLS12
+	}
+	clientAuth := r.opts.clientAuth()
+	build := func() *tls.Config {
+		r.mu.RLock()
+		defer r.mu.RUnlock()
+		return &tls.Config{
+			Certificates: []tls.Certificate{*r.cert},
+			ClientCAs:    r.clientCAs,
+			ClientAuth:   clientAuth,
+			MinVersion:   minVersion,
+			NextProtos:   nextProtos,
+		}
+	}
+	return &tls.Config{
+		MinVersion: minVersion,
+		NextProtos: nextProtos,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			r.maybeReload()
+			return build(), nil
+		},
+	}
+}
+
+// GrpcServerTLS 根据 opts 生成 gRPC 的 TLS ServerOption，证书同样支持热加载。
+// 传给 NewGrpcServer 时会覆盖默认（SetTLS / 环境变量）的证书配置。
+func GrpcServerTLS(opts *TLSOptions) (grpc.ServerOption, error) {
+	r, err := newCertReloader(opts)
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Creds(credentials.NewTLS(r.config("h2"))), nil
+}
@@ -9,6 +9,7 @@ import (
 	"net/http"
 	"strings"
 	"sync"
+	"sync/atomic"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -20,7 +21,9 @@ const ContextLoginIdKey = "ATOKEN_CONTEXT_LOGIN_ID_KEY"
 const ContextBasicAuthKey = "ATOKEN_BASIC_AUTH_KEY"
 
 var _defaultTokenHandler TokenHandler
-var _defaultTokenTimeout int64 = 30 * 24 * 60 * 60
+
+// _defaultTokenTimeout token 有效期（秒），初始取 Config.Auth.TokenTimeout，配置重载时可能被并发修改。
+var _defaultTokenTimeout atomic.Int64
 
 // _basicAuthValidator 由业务层注册，用于校验 Basic Auth 凭据。
 // 若未注册则拒绝所有 Basic Auth 请求。
@@ -93,14 +96,14 @@ func SetTokenHandler(handler TokenHandler) {
 
 
 func SetTokenTimeout(timeout int64) {
-	_defaultTokenTimeout = timeout
+	_defaultTokenTimeout.Store(timeout)
 }
 
 func Login(ctx *gin.Context, loginId int64) (string, error) {
 	token := &Token{
 		Value:     UUID(),
 		Name:      "Bearer",
-		Timeout:   _defaultTokenTimeout,
+		Timeout:   _defaultTokenTimeout.Load(),
 		LoginId:   loginId,
 		CreatedAt: time.Now().Unix(),
 	}
@@ -185,7 +188,7 @@ func (d *redisTokenStore) StoreToken(ctx context.Context, key string, token *Tok
 	if err != nil {
 		return fmt.Errorf("marshal token: %w", err)
 	}
-	return Redis().Set(ctx, redisTokenPrefix+key, string(jsonData), time.Duration(_defaultTokenTimeout)*time.Second).Err()
+	return Redis().Set(ctx, redisTokenPrefix+key, string(jsonData), time.Duration(_defaultTokenTimeout.Load())*time.Second).Err()
 }
 
 func (d *redisTokenStore) LoadToken(ctx context.Context, key string) (*Token, error) {
@@ -201,6 +204,7 @@ func (d *redisTokenStore) LoadToken(ctx context.Context, key string) (*Token, er
 }
 
 func init() {
+	_defaultTokenTimeout.Store(int64(conf().Auth.TokenTimeout / time.Second))
 	if HasRedis() {
 		_defaultTokenHandler = &redisTokenStore{}
 	} else {
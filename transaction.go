@@ -3,9 +3,11 @@ package gowk
 import (
 	"context"
 	"errors"
+	"fmt"
 	"github.com/gin-gonic/gin"
 	"github.com/jackc/pgx/v5"
 	"log/slog"
+	"slices"
 	"sync"
 )
 
@@ -16,21 +18,77 @@ const (
 type Transaction struct {
 	Begin bool
 	mu    sync.Mutex
-	Tx    pgx.Tx
+	// Tx 默认实例上的事务。
+	Tx pgx.Tx
+	// named 命名实例上的事务，见 PostgresNamedTx。
+	named map[string]pgx.Tx
+}
+
+func (tx *Transaction) get(name string) pgx.Tx {
+	if name == "" {
+		return tx.Tx
+	}
+	return tx.named[name]
+}
+
+func (tx *Transaction) set(name string, t pgx.Tx) {
+	if name == "" {
+		tx.Tx = t
+		return
+	}
+	if tx.named == nil {
+		tx.named = map[string]pgx.Tx{}
+	}
+	tx.named[name] = t
+}
+
+// started 是否已在某个实例上开启了事务。
+func (tx *Transaction) started() bool {
+	return tx.Tx != nil || len(tx.named) > 0
+}
+
+// finish 依次提交或回滚默认实例与各命名实例（按名字排序）上的事务。提交失败时其余事务改为回滚，
+// 返回提交失败的 error；回滚失败只记日志。
+func (tx *Transaction) finish(ctx context.Context, commit bool) error {
+	names := []string{""}
+	for name := range tx.named {
+		names = append(names, name)
+	}
+	slices.Sort(names[1:])
+	var commitErr error
+	for _, name := range names {
+		t := tx.get(name)
+		if t == nil {
+			continue
+		}
+		if commit && commitErr == nil {
+			if err := t.Commit(ctx); err != nil {
+				slog.ErrorContext(ctx, "事务提交失败", "db", name, "err", err)
+				commitErr = fmt.Errorf("事务提交失败: %w", err)
+			}
+		} else if err := t.Rollback(ctx); err != nil {
+			slog.ErrorContext(ctx, "事务回滚失败", "db", name, "err", err)
+		}
+	}
+	tx.Begin = false
+	tx.Tx = nil
+	tx.named = nil
+	return commitErr
 }
 
 func TransactionHandler() gin.HandlerFunc {
 	return func(ctx *gin.Context) {
 		ctx.Set(TRANSACTION, &Transaction{})
 		defer func() {
-			if tx, ok := ctx.Value(TRANSACTION).(*Transaction); ok && tx != nil && tx.Begin && tx.Tx != nil {
+			if tx, ok := ctx.Value(TRANSACTION).(*Transaction); ok && tx != nil && tx.Begin && tx.started() {
 				if err := recover(); err != nil {
 					Rollback(ctx)
 					panic(err)
 				} else if len(ctx.Errors) > 0 {
 					Rollback(ctx)
-				} else {
-					Commit(ctx)
+				} else if err := Commit(ctx); err != nil {
+					// 响应通常已经写出，这里至少让外层的 GlobalErrorHandler / 日志看到提交失败。
+					ctx.Error(err)
 				}
 			}
 		}()
@@ -51,29 +109,28 @@ func Begin(ctx context.Context) error {
 	return nil
 }
 
-func End(ctx context.Context, err error) {
+// End err 不为 nil 时回滚，否则提交；返回 err 或提交失败的 error。
+func End(ctx context.Context, err error) error {
 	if err != nil {
 		Rollback(ctx)
-	} else {
-		Commit(ctx)
+		return err
 	}
+	return Commit(ctx)
 }
 
-func Commit(ctx context.Context) {
+// Commit 提交已开启的事务，提交失败时返回 error（其余实例上的事务会被回滚）。
+func Commit(ctx context.Context) error {
 	v := ctx.Value(TRANSACTION)
 	tx, _ := v.(*Transaction)
 	if tx == nil {
-		return
+		return nil
 	}
 	tx.mu.Lock()
 	defer tx.mu.Unlock()
-	if tx.Begin && tx.Tx != nil {
-		if err := tx.Tx.Commit(ctx); err != nil {
-			slog.ErrorContext(ctx, "事务提交失败", "err", err)
-		}
-		tx.Begin = false
-		tx.Tx = nil
+	if tx.Begin && tx.started() {
+		return tx.finish(ctx, true)
 	}
+	return nil
 }
 
 func Rollback(ctx context.Context) {
@@ -84,11 +141,7 @@ func Rollback(ctx context.Context) {
 	}
 	tx.mu.Lock()
 	defer tx.mu.Unlock()
-	if tx.Begin && tx.Tx != nil {
-		if err := tx.Tx.Rollback(ctx); err != nil {
-			slog.ErrorContext(ctx, "事务回滚失败", "err", err)
-		}
-		tx.Begin = false
-		tx.Tx = nil
+	if tx.Begin && tx.started() {
+		_ = tx.finish(ctx, false)
 	}
 }
@@ -0,0 +1,87 @@
+package gowk
+
+import (
+	"errors"
+	"fmt"
+	"io/fs"
+	"net"
+	"os"
+	"os/user"
+	"strconv"
+	"time"
+)
+
+// unixScheme 是 Unix socket 监听地址的前缀，如 HTTP_SERVER_ADDR=unix:///run/app/http.sock。
+const unixScheme = "unix://"
+
+// listenUnix 在 path 上创建 Unix socket：
+//   - 已存在的 socket 文件若无人监听（上次异常退出残留）先删除，仍有进程在监听则返回 error；
+//   - 已存在但不是 socket 的文件不删除，直接返回 error；
+//   - 创建后按 UNIX_SOCKET_MODE / UNIX_SOCKET_GROUP 设置权限，listener 关闭时删除 socket 文件。
+func listenUnix(path string) (net.Listener, error) {
+	if err := removeStaleSocket(path); err != nil {
+		return nil, err
+	}
+	ln, err := net.Listen("unix", path)
+	if err != nil {
+		return nil, err
+	}
+	ln.(*net.UnixListener).SetUnlinkOnClose(true)
+	if err := chmodSocket(path); err != nil {
+		_ = ln.Close()
+		return nil, err
+	}
+	return ln, nil
+}
+
+func removeStaleSocket(path string) error {
+	fi, err := os.Lstat(path)
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	if fi.Mode()&fs.ModeSocket == 0 {
+		return fmt.Errorf("%s 已存在且不是 socket 文件", path)
+	}
+	if conn, err := net.DialTimeout("unix", path, time.Second); err == nil {
+		_ = conn.Close()
+		return fmt.Errorf("%s 仍有进程在监听", path)
+	}
+	return os.Remove(path)
+}
+
+func chmodSocket(path string) error {
+	if conf().Server.UnixSocketMode != 0 {
+		if err := os.Chmod(path, conf().Server.UnixSocketMode); err != nil {
+			return fmt.Errorf("设置 socket 权限失败: %w", err)
+		}
+	}
+	if conf().Server.UnixSocketGroup == "" {
+		return nil
+	}
+	gid, err := strconv.Atoi(conf().Server.UnixSocketGroup)
+	if err != nil {
+		g, err := user.LookupGroup(conf().Server.UnixSocketGroup)
+		if err != nil {
+			return fmt.Errorf("查找 socket 属组失败: %w", err)
+		}
+		gid, _ = strconv.Atoi(g.Gid)
+	}
+	if err := os.Chown(path, -1, gid); err != nil {
+		return fmt.Errorf("设置 socket 属组失败: %w", err)
+	}
+	return nil
+}
+
+// keepUnixSocketFiles 零停机重启把 socket 交给新进程后调用，本进程关闭监听时不再删除 socket 文件。
+func keepUnixSocketFiles() {
+	active.mu.Lock()
+	defer active.mu.Unlock()
+	for _, s := range active.files {
+		if ul, ok := s.(*net.UnixListener); ok {
+			ul.SetUnlinkOnClose(false)
+		}
+	}
+}
@@ -0,0 +1,79 @@
+package gowk
+
+import (
+	"context"
+	"errors"
+	"io"
+	"io/fs"
+	"net"
+	"net/http"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+)
+
+func TestUnixSocketListener(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	path := filepath.Join(t.TempDir(), "http.sock")
+	// 模拟上次异常退出残留的 socket 文件
+	stale, err := net.Listen("unix", path)
+	if err != nil {
+		t.Fatal(err)
+	}
+	stale.(*net.UnixListener).SetUnlinkOnClose(false)
+	stale.Close()
+
+	prevMode, prevGroup := conf().Server.UnixSocketMode, conf().Server.UnixSocketGroup
+	SetUnixSocketPermissions(0o660, "")
+	defer SetUnixSocketPermissions(prevMode, prevGroup)
+
+	engine := gin.New()
+	engine.GET("/ping", func(c *gin.Context) { c.String(http.StatusOK, "pong") })
+	h := &HttpServer{Engine: engine, Handler: &http.Server{Addr: unixScheme + path, Handler: engine}}
+	if err := h.ServerRun(); err != nil {
+		t.Fatal(err)
+	}
+	if fi, err := os.Stat(path); err != nil || fi.Mode().Perm() != 0o660 {
+		t.Fatalf("socket mode = %v, err = %v", fi.Mode(), err)
+	}
+
+	// 已有进程在监听时不能抢占
+	if _, err := listen(unixScheme + path); err == nil {
+		t.Fatal("listen on an active socket should fail")
+	}
+
+	client := &http.Client{Transport: &http.Transport{
+		DialContext: func(ctx context.Context, _, _ string) (net.Conn, error) {
+			return (&net.Dialer{}).DialContext(ctx, "unix", path)
+		},
+	}}
+	resp, err := client.Get("http://unix/ping")
+	if err != nil {
+		t.Fatal(err)
+	}
+	body, _ := io.ReadAll(resp.Body)
+	resp.Body.Close()
+	if string(body) != "pong" {
+		t.Fatalf("body = %q", body)
+	}
+
+	h.ServerStop()
+	if _, err := os.Stat(path); !errors.Is(err, fs.ErrNotExist) {
+		t.Fatalf("socket file not removed on stop: %v", err)
+	}
+}
+
+func TestUnixSocketRefusesRegularFile(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "not-a-socket")
+	if err := os.WriteFile(path, []byte("data"), 0o644); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := listen(unixScheme + path); err == nil {
+		t.Fatal("listen should refuse to remove a regular file")
+	}
+	if _, err := os.Stat(path); err != nil {
+		t.Fatalf("regular file removed: %v", err)
+	}
+}